@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/disgoorg/disgo"
@@ -19,9 +20,12 @@ import (
 	"github.com/disgoorg/disgo/gateway"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/stollenaar/ollamabot/internal/commands"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/listeners/dmlistener"
 	"github.com/stollenaar/ollamabot/internal/listeners/threadlistener"
 	"github.com/stollenaar/ollamabot/internal/routes"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 var (
@@ -35,32 +39,48 @@ var (
 
 func init() {
 	flag.Parse()
+	util.ConfigFile.DEBUG = *Debug
+}
 
+func createClient() {
 	c, err := disgo.New(util.GetDiscordToken(),
 		bot.WithGatewayConfigOpts(gateway.WithIntents(gateway.IntentDirectMessages |gateway.IntentGuildMessages | gateway.IntentMessageContent)),
 		bot.WithEventListenerFunc(func(event *events.ApplicationCommandInteractionCreate) {
-			data := event.SlashCommandInteractionData()
-			commands.CommandHandlers[data.CommandName()](event)
+			commands.CommandHandlers[event.Data.CommandName()](event)
 		}),
 		bot.WithEventListenerFunc(func(event *events.ModalSubmitInteractionCreate) {
-			commands.ModalSubmitHandlers[event.Data.CustomID](event)
+			commands.ModalSubmitHandlers[strings.Split(event.Data.CustomID, "_")[0]](event)
 		}),
 		bot.WithEventListenerFunc(func(event *events.ComponentInteractionCreate) {
 			commands.ComponentHandlers[strings.Split(event.Data.CustomID(), "_")[0]](event)
 		}),
 		bot.WithEventListenerFunc(threadlistener.Listener),
-		// bot.WithEventListenerFunc(dmlistener.Listener), // TODO
+		bot.WithEventListenerFunc(dmlistener.Listener),
 	)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 	client = c
-	util.ConfigFile.DEBUG = *Debug
 }
 
 func main() {
+	if flag.Arg(0) == "migrate" {
+		migrateCommand(flag.Args()[1:])
+		return
+	}
+
+	if err := database.Migrate(); err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+
+	backend, err := ollama.NewBackendFromConfig(util.ConfigFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ollama.Client = backend
 
+	createClient()
 	defer client.Close(context.TODO())
 	var guilds []snowflake.ID
 	if sn, err := snowflake.Parse(*GuildID); err == nil {
@@ -126,6 +146,11 @@ func main() {
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 
+	slog.Info("Stopping the running answers...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ollama.Shutdown(shutdownCtx)
+	cancel()
+
 	if *RemoveCommands {
 		log.Println("Removing commands...")
 		// We need to fetch the commands, since deleting requires the command ID.
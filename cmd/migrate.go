@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/stollenaar/ollamabot/internal/database"
+)
+
+const migrateUsage = `usage: ollamabot migrate <command>
+
+commands:
+  status    show the state of every migration
+  up        apply all pending migrations
+  down N    roll back the last N applied migrations
+  repair    clear failed records and accept changed checksums`
+
+// migrateCommand runs the migrate subcommand without starting the bot
+func migrateCommand(args []string) {
+	defer database.Exit()
+
+	if len(args) == 0 {
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "status":
+		migrations, err := database.ListMigrations()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tSTATUS\tAPPLIED AT\tDOWN")
+		for _, m := range migrations {
+			appliedAt := "-"
+			if m.Recorded {
+				appliedAt = m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", m.ID, m.Name, m.Status(), appliedAt, m.HasDown)
+		}
+		w.Flush()
+	case "up":
+		if err := database.Migrate(); err != nil {
+			log.Fatalf("migration failed: %v", err)
+		}
+	case "down":
+		if len(args) < 2 {
+			log.Fatal("migrate down requires the number of migrations to roll back")
+		}
+		steps, err := strconv.Atoi(args[1])
+		if err != nil || steps < 1 {
+			log.Fatalf("invalid number of migrations %q", args[1])
+		}
+		if err := database.MigrateDown(steps); err != nil {
+			log.Fatalf("rollback failed: %v", err)
+		}
+	case "repair":
+		repaired, err := database.RepairMigrations()
+		for _, r := range repaired {
+			log.Println(r)
+		}
+		if err != nil {
+			log.Fatalf("repair failed: %v", err)
+		}
+		if len(repaired) == 0 {
+			log.Println("Nothing to repair")
+		}
+	default:
+		fmt.Println(migrateUsage)
+		os.Exit(2)
+	}
+}
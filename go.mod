@@ -8,10 +8,12 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/ssm v1.65.1
 	github.com/bwmarrin/discordgo v0.29.0
 	github.com/disgoorg/disgo v0.19.0-rc.6
+	github.com/disgoorg/omit v1.0.0
 	github.com/disgoorg/snowflake/v2 v2.0.3
+	github.com/gin-gonic/gin v1.11.0
 	github.com/joho/godotenv v1.5.1
 	github.com/marcboeker/go-duckdb/v2 v2.4.1
-	github.com/ollama/ollama v0.12.3
+	github.com/ollama/ollama v0.13.5
 	github.com/stollenaar/aws-rotating-credentials-provider/credentials v0.0.0-20250330204128-299effe6093c
 	github.com/stollenaar/ollamabot/internal/routes v0.0.0-20251227180417-227b07b12839
 )
@@ -35,7 +37,6 @@ require (
 	github.com/bytedance/sonic/loader v0.3.0 // indirect
 	github.com/cloudwego/base64x v0.1.6 // indirect
 	github.com/disgoorg/json/v2 v2.0.0 // indirect
-	github.com/disgoorg/omit v1.0.0 // indirect
 	github.com/duckdb/duckdb-go-bindings v0.1.20 // indirect
 	github.com/duckdb/duckdb-go-bindings/darwin-amd64 v0.1.20 // indirect
 	github.com/duckdb/duckdb-go-bindings/darwin-arm64 v0.1.20 // indirect
@@ -45,7 +46,6 @@ require (
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.10 // indirect
 	github.com/gin-contrib/sse v1.1.0 // indirect
-	github.com/gin-gonic/gin v1.11.0 // indirect
 	github.com/go-openapi/jsonpointer v0.22.1 // indirect
 	github.com/go-openapi/jsonreference v0.21.2 // indirect
 	github.com/go-openapi/spec v0.22.0 // indirect
@@ -108,3 +108,5 @@ require (
 	google.golang.org/protobuf v1.36.9 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 )
+
+replace github.com/stollenaar/ollamabot/internal/routes => ./internal/routes
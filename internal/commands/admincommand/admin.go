@@ -1,7 +1,6 @@
 package admincommand
 
 import (
-	"log"
 	"log/slog"
 	"strings"
 
@@ -16,7 +15,6 @@ var (
 		Name:        "admin",
 		Description: "Admin command to manage to ollamabot",
 	}
-	OllamaClient *ollamaApi.Client
 )
 
 type AdminCommand struct {
@@ -24,14 +22,6 @@ type AdminCommand struct {
 	Description string
 }
 
-func init() {
-	client, err := ollamaApi.ClientFromEnvironment()
-	if err != nil {
-		log.Fatal(err)
-	}
-	OllamaClient = client
-}
-
 func (a AdminCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
 	if event.Member().User.ID.String() != util.ConfigFile.ADMIN_USER_ID {
 		event.CreateMessage(discord.MessageCreate{
@@ -40,6 +30,14 @@ func (a AdminCommand) Handler(event *events.ApplicationCommandInteractionCreate)
 		})
 		return
 	}
+	sub := event.SlashCommandInteractionData()
+	if *sub.SubCommandGroupName == "ollama" && *sub.SubCommandName == "create" {
+		if err := event.Modal(createModal()); err != nil {
+			slog.Error("Error creating modal: ", slog.Any("err", err))
+		}
+		return
+	}
+
 	err := event.DeferCreateMessage(true)
 
 	if err != nil {
@@ -47,8 +45,6 @@ func (a AdminCommand) Handler(event *events.ApplicationCommandInteractionCreate)
 		return
 	}
 
-	sub := event.SlashCommandInteractionData()
-
 	var components []discord.LayoutComponent
 	switch *sub.SubCommandGroupName {
 	case "platform":
@@ -61,10 +57,40 @@ func (a AdminCommand) Handler(event *events.ApplicationCommandInteractionCreate)
 		components = platformModelHandler(sub, event)
 	case "prompt":
 		components = promptHandler(sub, event)
+	case "apikey":
+		components = apiKeyHandler(sub, event)
+	case "kb":
+		components = kbHandler(sub, event)
 	}
 	util.UpdateInteractionResponse(event, components)
 }
 
+func (a AdminCommand) ModalHandler(event *events.ModalSubmitInteractionCreate) {
+	if event.User().ID.String() != util.ConfigFile.ADMIN_USER_ID {
+		return
+	}
+
+	err := event.DeferCreateMessage(true)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	var components []discord.LayoutComponent
+	switch event.Data.CustomID {
+	case "admin_ollama_create":
+		components = createModel(event)
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Components: &components,
+		Flags:      util.ConfigFile.SetComponentV2Flags(),
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
 func (a AdminCommand) ComponentHandler(event *events.ComponentInteractionCreate) {
 	if event.Member().User.ID.String() != util.ConfigFile.ADMIN_USER_ID {
 		return
@@ -81,6 +107,8 @@ func (a AdminCommand) ComponentHandler(event *events.ComponentInteractionCreate)
 	switch strings.Split(event.Data.CustomID(), "_")[1] {
 	case "prompt":
 		components = promptButtonHandler(event)
+	case "ollama":
+		components = ollamaButtonHandler(event)
 	default:
 		components = append(components, discord.ContainerComponent{
 			Components: []discord.ContainerSubComponent{
@@ -113,8 +141,62 @@ func (a AdminCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 							Description: "Model to pull to use",
 							Required:    true,
 						},
+						discord.ApplicationCommandOptionBool{
+							Name:        "add",
+							Description: "Add the model to the bot when the pull finishes",
+						},
 					},
 				},
+				{
+					Name:        "delete",
+					Description: "Deletes a model from Ollama",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "model",
+							Description: "Model to delete",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "copy",
+					Description: "Copies a model to a new name",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "source",
+							Description: "Model to copy",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionString{
+							Name:        "destination",
+							Description: "Name of the copy",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "show",
+					Description: "Shows the parameters, template, license and Modelfile of a model",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "model",
+							Description: "Model to show",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "ps",
+					Description: "Lists the models loaded in memory",
+				},
+				{
+					Name:        "create",
+					Description: "Creates a model from a Modelfile",
+				},
+				{
+					Name:        "hosts",
+					Description: "Shows the health and models of the Ollama hosts",
+				},
 			},
 		},
 		discord.ApplicationCommandOptionSubCommandGroup{
@@ -136,6 +218,37 @@ func (a AdminCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 						},
 					},
 				},
+				{
+					Name:        "options",
+					Description: "Set the default generation options of a model",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "Name of the model",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionString{
+							Name:        "options",
+							Description: "Options like temperature=0.7 num_ctx=8192 stop=\"User:\", leave empty to clear them",
+						},
+					},
+				},
+				{
+					Name:        "vision",
+					Description: "Override if a model accepts images",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "Name of the model",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionBool{
+							Name:        "enabled",
+							Description: "If the model accepts images",
+							Required:    true,
+						},
+					},
+				},
 				{
 					Name:        "remove",
 					Description: "Remove a llm model",
@@ -189,6 +302,102 @@ func (a AdminCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 					Name:        "list",
 					Description: "List all platforms",
 				},
+				{
+					Name:        "grant",
+					Description: "Grant coins on a platform to a user",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionUser{
+							Name:        "user",
+							Description: "User to grant the coins to",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionString{
+							Name:        "id",
+							Description: "ID of the platform",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionInt{
+							Name:        "coins",
+							Description: "Amount of coins, negative to take coins away",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommandGroup{
+			Name:        "apikey",
+			Description: "api key subcommands",
+			Options: []discord.ApplicationCommandOptionSubCommand{
+				{
+					Name:        "issue",
+					Description: "Issue an api key for a platform",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "id",
+							Description: "ID of the platform",
+							Required:    true,
+						},
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "Name to recognise the key by",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "revoke",
+					Description: "Revoke an api key",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "key_id",
+							Description: "ID of the key",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List the issued api keys",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "id",
+							Description: "ID of the platform",
+						},
+					},
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommandGroup{
+			Name:        "kb",
+			Description: "knowledge base subcommands",
+			Options: []discord.ApplicationCommandOptionSubCommand{
+				{
+					Name:        "list",
+					Description: "List the knowledge bases with their documents",
+				},
+				{
+					Name:        "remove",
+					Description: "Remove a knowledge base, its threads continue without",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "Name of the knowledge base",
+							Required:    true,
+						},
+					},
+				},
+				{
+					Name:        "reindex",
+					Description: "Embed the documents of a knowledge base again, like after changing the embedding model",
+					Options: []discord.ApplicationCommandOption{
+						discord.ApplicationCommandOptionString{
+							Name:        "name",
+							Description: "Name of the knowledge base",
+							Required:    true,
+						},
+					},
+				},
 			},
 		},
 		discord.ApplicationCommandOptionSubCommandGroup{
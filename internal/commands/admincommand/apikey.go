@@ -0,0 +1,99 @@
+package admincommand
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+func apiKeyHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) (components []discord.LayoutComponent) {
+	switch *args.SubCommandName {
+	case "issue":
+		platform, err := database.GetPlatform(args.String("id"))
+		if err != nil {
+			slog.Error("Error fetching platform: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		key, apiKey, err := database.CreateAPIKey(platform.ID, args.String("name"))
+		if err != nil {
+			slog.Error("Error issuing api key: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		components = []discord.LayoutComponent{
+			discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### Issued key %s for %s\n```\n%s\n```\nThis key is only shown once, send it as a bearer token or as the password of basic auth with `%s` as username", apiKey.ID, platform.Name, key, platform.ID),
+					},
+				},
+			},
+		}
+	case "revoke":
+		err := database.RevokeAPIKey(args.String("key_id"))
+		if err == sql.ErrNoRows {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("No active key with id %s", args.String("key_id")),
+				},
+			}
+		} else if err != nil {
+			slog.Error("Error revoking api key: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		} else {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: "Successfully revoked the key",
+				},
+			}
+		}
+	case "list":
+		apiKeys, err := database.ListAPIKeys(args.String("id"))
+		if err != nil {
+			slog.Error("Error listing api keys: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		for _, apiKey := range apiKeys {
+			owner := fmt.Sprintf("Platform: %s", apiKey.PlatformID)
+			if apiKey.UserID != "" {
+				owner = fmt.Sprintf("User: <@%s>", apiKey.UserID)
+			}
+			lastUsed, status := "never", "active"
+			if apiKey.LastUsedAt != nil {
+				lastUsed = fmt.Sprintf("<t:%d:R>", apiKey.LastUsedAt.Unix())
+			}
+			if apiKey.RevokedAt != nil {
+				status = fmt.Sprintf("revoked <t:%d:R>", apiKey.RevokedAt.Unix())
+			}
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### ID: %s\n### %s\n### Name: %s\n### Last used: %s\n### Status: %s", apiKey.ID, owner, apiKey.Name, lastUsed, status),
+					},
+				},
+			})
+		}
+
+		if len(apiKeys) == 0 {
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: "No api keys issued",
+					},
+				},
+			})
+		}
+	}
+	return
+}
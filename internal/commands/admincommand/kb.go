@@ -0,0 +1,112 @@
+package admincommand
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+	"github.com/stollenaar/ollamabot/internal/util/retrieval"
+)
+
+func kbHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) (components []discord.LayoutComponent) {
+	switch *args.SubCommandName {
+	case "list":
+		knowledgeBases, err := database.ListKnowledgeBases()
+		if err != nil {
+			slog.Error("Error listing knowledge bases: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		for _, knowledgeBase := range knowledgeBases {
+			documents, err := database.GetKnowledgeDocuments(knowledgeBase.Name)
+			if err != nil {
+				slog.Error("Error listing documents: ", slog.Any("err", err))
+			}
+			var files string
+			for _, document := range documents {
+				files += fmt.Sprintf("\n- %s", document.FileName)
+			}
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### %s\n**Created by:** <@%s> <t:%d:R>\n**Chunks:** %d\n**Documents:** %d%s",
+							knowledgeBase.Name, knowledgeBase.CreatedBy, knowledgeBase.CreatedAt.Unix(), knowledgeBase.Chunks, knowledgeBase.Documents, files),
+					},
+				},
+			})
+		}
+
+		if len(knowledgeBases) == 0 {
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: "No knowledge bases yet, add documents with /kb add",
+					},
+				},
+			})
+		}
+	case "remove":
+		err := database.RemoveKnowledgeBase(args.String("name"))
+		if err == sql.ErrNoRows {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("No knowledge base named %s", args.String("name")),
+				},
+			}
+		} else if err != nil {
+			slog.Error("Error removing knowledge base: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		} else {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: "Successfully removed the knowledge base",
+				},
+			}
+		}
+	case "reindex":
+		documents, err := database.GetKnowledgeDocuments(args.String("name"))
+		if err != nil {
+			slog.Error("Error listing documents: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+		if len(documents) == 0 {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("No knowledge base named %s", args.String("name")),
+				},
+			}
+			return
+		}
+
+		ctx, done := ollama.NewRequest(context.Background(), event.ID().String(), event.Channel().ID().String(), event.User().ID.String())
+		defer done()
+
+		chunks, err := retrieval.EmbedDocuments(ctx, event.User().ID.String(), documents...)
+		if err != nil {
+			slog.Error("Error embedding documents: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+		if err := database.ReplaceKnowledgeChunks(args.String("name"), chunks); err != nil {
+			slog.Error("Error saving chunks: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		components = []discord.LayoutComponent{
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("Reindexed %d documents in %d chunks with %s", len(documents), len(chunks), ollama.EmbeddingModel()),
+			},
+		}
+	}
+	return
+}
@@ -2,6 +2,7 @@ package admincommand
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log/slog"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/disgoorg/disgo/events"
 	"github.com/stollenaar/ollamabot/internal/database"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 func modelHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) (components []discord.LayoutComponent) {
@@ -16,7 +18,7 @@ func modelHandler(args discord.SlashCommandInteractionData, event *events.Applic
 	case "add":
 		model := args.Options["model"].String()
 
-		resp, err := OllamaClient.List(context.TODO())
+		resp, err := ollama.Client.List(context.TODO())
 		if err != nil {
 			slog.Error("Error listing models: ", slog.Any("err", err))
 			return
@@ -31,20 +33,76 @@ func modelHandler(args discord.SlashCommandInteractionData, event *events.Applic
 			return
 		}
 
-		err = database.AddModel(model)
+		details, err := addModel(model)
 		if err != nil {
 			slog.Error("Error creating model: ", slog.Any("err", err))
 			util.RespondWithError(event, err)
 		} else {
+			content := "Successfully added the model"
+			if modelDetails := details.Details(); modelDetails != "" {
+				content += "\n-# " + modelDetails
+			}
 			components = []discord.LayoutComponent{
 				discord.TextDisplayComponent{
-					Content: "Successfully added the model",
+					Content: content,
 				},
 			}
 			util.UpdateInteractionResponse(event, components)
 		}
+	case "vision":
+		err := database.SetModelVision(args.String("name"), args.Bool("enabled"))
+		if err == sql.ErrNoRows {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("Model %s is not added", args.String("name")),
+				},
+			}
+		} else if err != nil {
+			slog.Error("Error updating model: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		} else {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("Successfully updated the vision of %s", args.String("name")),
+				},
+			}
+		}
+	case "options":
+		options, err := ollama.ParseOptions(args.String("options"))
+		if err != nil {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("Invalid options: %s", err),
+				},
+			}
+			return
+		}
+
+		err = database.SetModelOptions(args.String("name"), options)
+		if err == sql.ErrNoRows {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("Model %s is not added", args.String("name")),
+				},
+			}
+		} else if err != nil {
+			slog.Error("Error updating model: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		} else {
+			content := fmt.Sprintf("%s now uses the Ollama defaults", args.String("name"))
+			if len(options) > 0 {
+				content = fmt.Sprintf("Updated the options of %s to `%s`", args.String("name"), ollama.FormatOptions(options))
+			}
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: content,
+				},
+			}
+		}
 	case "list":
-		models, err := database.ListModels()
+		models, err := database.ListModelDetails()
 
 		if err != nil {
 			slog.Error("Error listing platforms: ", slog.Any("err", err))
@@ -53,10 +111,17 @@ func modelHandler(args discord.SlashCommandInteractionData, event *events.Applic
 		}
 
 		for _, model := range models {
+			content := fmt.Sprintf("### Name: %s\n### Vision: %t", model.Name, model.Vision)
+			if options := ollama.FormatOptions(ollama.Options(model.Name)); options != "" {
+				content += fmt.Sprintf("\n### Options: `%s`", options)
+			}
+			if details := model.Details(); details != "" {
+				content += "\n-# " + details
+			}
 			container := discord.ContainerComponent{
 				Components: []discord.ContainerSubComponent{
 					discord.TextDisplayComponent{
-						Content: fmt.Sprintf("### Name: %s", model),
+						Content: content,
 					},
 				},
 			}
@@ -4,54 +4,177 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 func ollamaHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) (components []discord.LayoutComponent) {
 	switch *args.SubCommandName {
 	case "pull":
-		err := OllamaClient.Pull(context.TODO(), &ollamaApi.PullRequest{
-			Model: args.Options["model"].String(),
-		}, func(pr ollamaApi.ProgressResponse) error {
-			return nil
+		components = startPull(event, args.String("model"), args.Bool("add"))
+	case "list":
+		resp, err := ollama.Client.List(context.TODO())
+		if err != nil {
+			slog.Error("Error listing models: ", slog.Any("err", err))
+			return
+		}
+
+		for _, model := range resp.Models {
+			container := discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### Name: %s", model.Name),
+					},
+				},
+			}
+			components = append(components, container)
+		}
+
+		if len(resp.Models) == 0 {
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: "No Ollama Models Available",
+					},
+				},
+			})
+		}
+	case "delete":
+		model := args.String("model")
+		if _, err := database.GetModel(model); err == nil {
+			components = []discord.LayoutComponent{
+				discord.ContainerComponent{
+					Components: []discord.ContainerSubComponent{
+						discord.TextDisplayComponent{
+							Content: fmt.Sprintf("%s is still added to the bot, prompts using it fail once it's deleted. Remove it with /admin model remove first, or delete it anyway", model),
+						},
+						discord.ActionRowComponent{
+							Components: []discord.InteractiveComponent{
+								discord.ButtonComponent{
+									Style:    discord.ButtonStyleDanger,
+									Label:    "Delete anyway",
+									CustomID: fmt.Sprintf("admin_ollama_delete_%s", model),
+								},
+							},
+						},
+					},
+				},
+			}
+			return
+		}
+
+		components = deleteModel(model)
+	case "copy":
+		err := ollama.Client.Copy(context.TODO(), &ollamaApi.CopyRequest{
+			Source:      args.String("source"),
+			Destination: args.String("destination"),
 		})
 		if err != nil {
-			slog.Error("Error pulling model: ", slog.Any("err", err))
+			slog.Error("Error copying model: ", slog.Any("err", err))
 			util.RespondWithError(event, err)
 			return
 		}
 		components = []discord.LayoutComponent{
 			discord.TextDisplayComponent{
-				Content: "Pulled model",
+				Content: fmt.Sprintf("Copied %s to %s", args.String("source"), args.String("destination")),
 			},
 		}
-	case "list":
-		resp, err := OllamaClient.List(context.TODO())
+	case "show":
+		model := args.String("model")
+		show, err := ollama.Client.Show(context.TODO(), &ollamaApi.ShowRequest{Model: model})
 		if err != nil {
-			slog.Error("Error listing models: ", slog.Any("err", err))
+			slog.Error("Error showing model: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		// Components V2 messages hold at most 4000 characters of text
+		container := discord.ContainerComponent{
+			Components: []discord.ContainerSubComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("### %s\n**Family:** %s\n**Parameters:** %s\n**Quantization:** %s\n**Capabilities:** %v",
+						model, show.Details.Family, show.Details.ParameterSize, show.Details.QuantizationLevel, show.Capabilities),
+				},
+			},
+		}
+		for _, section := range []struct{ title, content string }{
+			{"Parameters", show.Parameters},
+			{"Template", show.Template},
+			{"License", show.License},
+			{"Modelfile", show.Modelfile},
+		} {
+			if strings.TrimSpace(section.content) == "" {
+				continue
+			}
+			container.Components = append(container.Components, discord.TextDisplayComponent{
+				Content: fmt.Sprintf("**%s**\n```\n%s\n```", section.title, truncate(section.content, 700)),
+			})
+		}
+		components = []discord.LayoutComponent{container}
+	case "ps":
+		resp, err := ollama.Client.ListRunning(context.TODO())
+		if err != nil {
+			slog.Error("Error listing running models: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
 			return
 		}
 
 		for _, model := range resp.Models {
-			container := discord.ContainerComponent{
+			components = append(components, discord.ContainerComponent{
 				Components: []discord.ContainerSubComponent{
 					discord.TextDisplayComponent{
-						Content: fmt.Sprintf("### Name: %s", model.Name),
+						Content: fmt.Sprintf("### Name: %s\n### Size: %s\n### VRAM: %s\n### Context: %d\n### Unloads: <t:%d:R>",
+							model.Name, formatBytes(model.Size), formatBytes(model.SizeVRAM), model.ContextLength, model.ExpiresAt.Unix()),
 					},
 				},
-			}
-			components = append(components, container)
+			})
 		}
 
 		if len(resp.Models) == 0 {
 			components = append(components, discord.ContainerComponent{
 				Components: []discord.ContainerSubComponent{
 					discord.TextDisplayComponent{
-						Content: "No Ollama Models Available",
+						Content: "No models are loaded",
+					},
+				},
+			})
+		}
+	case "hosts":
+		for _, host := range ollama.Hosts() {
+			status := "🟢 healthy"
+			if !host.Healthy {
+				status = fmt.Sprintf("🔴 unhealthy: %s", host.Err)
+			}
+			checked := "not checked yet"
+			if !host.Checked.IsZero() {
+				checked = fmt.Sprintf("checked <t:%d:R>", host.Checked.Unix())
+			}
+			loaded := "none"
+			if len(host.Loaded) > 0 {
+				loaded = strings.Join(host.Loaded, ", ")
+			}
+
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### Host: %s\n### Status: %s\n### Models: %d\n### Loaded: %s\n-# %s",
+							host.Name, status, len(host.Models), loaded, checked),
+					},
+				},
+			})
+		}
+
+		if len(components) == 0 {
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: "A single Ollama host is configured, set OLLAMA_HOSTS to spread requests over several hosts",
 					},
 				},
 			})
@@ -59,3 +182,115 @@ func ollamaHandler(args discord.SlashCommandInteractionData, event *events.Appli
 	}
 	return
 }
+
+// ollamaButtonHandler handles the pull cancel and delete confirmation buttons
+func ollamaButtonHandler(event *events.ComponentInteractionCreate) []discord.LayoutComponent {
+	parts := strings.Split(event.Data.CustomID(), "_")
+	if len(parts) < 4 {
+		return []discord.LayoutComponent{}
+	}
+
+	switch parts[2] {
+	case "cancel":
+		content := "Cancelling the pull"
+		if !cancelPull(parts[3]) {
+			content = "The pull already finished"
+		}
+		return []discord.LayoutComponent{
+			discord.TextDisplayComponent{
+				Content: content,
+			},
+		}
+	case "delete":
+		// Model names can hold underscores
+		return deleteModel(strings.Join(parts[3:], "_"))
+	default:
+		return []discord.LayoutComponent{}
+	}
+}
+
+// createModal asks for the name and Modelfile of the model to create
+func createModal() discord.ModalCreate {
+	return discord.ModalCreate{
+		CustomID: "admin_ollama_create",
+		Title:    "Create Ollama Model",
+		Components: []discord.LayoutComponent{
+			discord.LabelComponent{
+				Label: "Name",
+				Component: discord.TextInputComponent{
+					CustomID: "name",
+					Style:    discord.TextInputStyleShort,
+					Required: true,
+				},
+			},
+			discord.LabelComponent{
+				Label:       "Modelfile",
+				Description: "FROM an existing model with PARAMETER, TEMPLATE, SYSTEM, LICENSE and MESSAGE lines",
+				Component: discord.TextInputComponent{
+					CustomID:    "modelfile",
+					Style:       discord.TextInputStyleParagraph,
+					Required:    true,
+					Placeholder: "FROM llama3.2\nPARAMETER temperature 0.7\nSYSTEM You are a helpful assistant",
+				},
+			},
+		},
+	}
+}
+
+// createModel creates the model of the submitted Modelfile
+func createModel(event *events.ModalSubmitInteractionCreate) []discord.LayoutComponent {
+	name := event.Data.Text("name")
+	req, err := ollama.CreateRequest(name, event.Data.Text("modelfile"))
+	if err == nil {
+		var status string
+		err = ollama.Client.Create(context.TODO(), req, func(pr ollamaApi.ProgressResponse) error {
+			status = pr.Status
+			return nil
+		})
+		if err == nil {
+			return []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("Created %s from %s, %s\n-# Add it to the bot with /admin model add", name, req.From, status),
+				},
+			}
+		}
+	}
+
+	slog.Error("Error creating model: ", slog.Any("err", err))
+	return []discord.LayoutComponent{
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("Failed to create %s: %s", name, err),
+		},
+	}
+}
+
+func deleteModel(model string) []discord.LayoutComponent {
+	err := ollama.Client.Delete(context.TODO(), &ollamaApi.DeleteRequest{Model: model})
+	if err != nil {
+		slog.Error("Error deleting model: ", slog.Any("err", err))
+		return []discord.LayoutComponent{
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("Failed to delete %s: %s", model, err),
+			},
+		}
+	}
+
+	content := fmt.Sprintf("Deleted %s from Ollama", model)
+	if _, err := database.GetModel(model); err == nil {
+		content += ", it is still added to the bot, remove it with /admin model remove"
+	}
+	return []discord.LayoutComponent{
+		discord.TextDisplayComponent{
+			Content: content,
+		},
+	}
+}
+
+// truncate cuts text to length runes
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "…"
+}
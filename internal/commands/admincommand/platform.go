@@ -61,6 +61,27 @@ func platformHandler(args discord.SlashCommandInteractionData, event *events.App
 			})
 		}
 
+	case "grant":
+		platform, err := database.GetPlatform(args.Options["id"].String())
+		if err != nil {
+			slog.Error("Error fetching platform: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		user := args.User("user")
+		err = database.AddBalance(user.ID.String(), platform.ID, args.Int("coins"))
+		if err != nil {
+			slog.Error("Error granting coins: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+		} else {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("Granted %d coins on %s to %s", args.Int("coins"), platform.Name, user.Username),
+				},
+			}
+			util.UpdateInteractionResponse(event, components)
+		}
 	case "remove":
 		err := database.RemovePlatform(args.Options["id"].String())
 		if err != nil {
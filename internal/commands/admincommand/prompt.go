@@ -13,6 +13,7 @@ import (
 	ollamaApi "github.com/ollama/ollama/api"
 	"github.com/stollenaar/ollamabot/internal/database"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 func promptHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) (components []discord.LayoutComponent) {
@@ -96,30 +97,27 @@ func promptHandler(args discord.SlashCommandInteractionData, event *events.Appli
 			return
 		}
 
-		OllamaClient.Generate(context.TODO(), &ollamaApi.GenerateRequest{
-			Model:  history.ModelName,
-			Prompt: history.Prompt,
-			Stream: new(bool),
+		ctx, done := ollama.NewRequest(context.Background(), event.ID().String(), event.Channel().ID().String(), event.User().ID.String())
+		defer done()
+
+		release, err := ollama.Queue.Acquire(ctx, history.ModelName, event.User().ID.String(), nil)
+		if err != nil {
+			slog.Error("Error waiting in the queue: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+		defer release()
+
+		ctx, cancel := ollama.WithGenerationTimeout(ctx)
+		defer cancel()
+
+		ollama.Client.Generate(ctx, &ollamaApi.GenerateRequest{
+			Model:   history.ModelName,
+			Prompt:  history.Prompt,
+			Stream:  new(bool),
+			Options: ollama.Options(history.ModelName),
 		}, func(gr ollamaApi.GenerateResponse) error {
-			components = []discord.LayoutComponent{
-				discord.TextDisplayComponent{
-					Content: gr.Response,
-				},
-				discord.ActionRowComponent{
-					Components: []discord.InteractiveComponent{
-						discord.ButtonComponent{
-							Style:    discord.ButtonStylePrimary,
-							Label:    "Post Prompt",
-							CustomID: "admin_prompt_page_post",
-						},
-						discord.ButtonComponent{
-							Style:    discord.ButtonStyleDanger,
-							Label:    "Retry Prompt",
-							CustomID: fmt.Sprintf("admin_prompt_page_retry_%d", history.ID),
-						},
-					},
-				},
-			}
+			components = replayComponents(gr.Response, history.ID)
 			return nil
 		})
 	}
@@ -158,30 +156,27 @@ func promptButtonHandler(event *events.ComponentInteractionCreate) (components [
 			return
 		}
 
-		OllamaClient.Generate(context.TODO(), &ollamaApi.GenerateRequest{
-			Model:  history.ModelName,
-			Prompt: history.Prompt,
-			Stream: new(bool),
+		ctx, done := ollama.NewRequest(context.Background(), event.ID().String(), event.Channel().ID().String(), event.User().ID.String())
+		defer done()
+
+		release, err := ollama.Queue.Acquire(ctx, history.ModelName, event.User().ID.String(), nil)
+		if err != nil {
+			slog.Error("Error waiting in the queue: ", slog.Any("err", err))
+			util.RespondWithErrorComponent(event, err)
+			return
+		}
+		defer release()
+
+		ctx, cancel := ollama.WithGenerationTimeout(ctx)
+		defer cancel()
+
+		ollama.Client.Generate(ctx, &ollamaApi.GenerateRequest{
+			Model:   history.ModelName,
+			Prompt:  history.Prompt,
+			Stream:  new(bool),
+			Options: ollama.Options(history.ModelName),
 		}, func(gr ollamaApi.GenerateResponse) error {
-			components = []discord.LayoutComponent{
-				discord.TextDisplayComponent{
-					Content: gr.Response,
-				},
-				discord.ActionRowComponent{
-					Components: []discord.InteractiveComponent{
-						discord.ButtonComponent{
-							Style:    discord.ButtonStylePrimary,
-							Label:    "Post Prompt",
-							CustomID: "admin_prompt_page_post",
-						},
-						discord.ButtonComponent{
-							Style:    discord.ButtonStyleDanger,
-							Label:    "Retry Prompt",
-							CustomID: fmt.Sprintf("admin_prompt_page_retry_%d", id),
-						},
-					},
-				},
-			}
+			components = replayComponents(gr.Response, id)
 			return nil
 		})
 		return components
@@ -190,6 +185,37 @@ func promptButtonHandler(event *events.ComponentInteractionCreate) (components [
 	}
 }
 
+// replayComponents shows a replayed response with the buttons to post or
+// retry it. Components V2 messages hold at most 4000 characters of text, so
+// a longer response is cut at a markdown boundary.
+func replayComponents(response string, id int) []discord.LayoutComponent {
+	contents := util.BreakContent(response, 3900)
+	content := contents[0]
+	if len(contents) > 1 {
+		content += fmt.Sprintf("\n-# Truncated, the full response has %d characters", len([]rune(response)))
+	}
+
+	return []discord.LayoutComponent{
+		discord.TextDisplayComponent{
+			Content: content,
+		},
+		discord.ActionRowComponent{
+			Components: []discord.InteractiveComponent{
+				discord.ButtonComponent{
+					Style:    discord.ButtonStylePrimary,
+					Label:    "Post Prompt",
+					CustomID: "admin_prompt_page_post",
+				},
+				discord.ButtonComponent{
+					Style:    discord.ButtonStyleDanger,
+					Label:    "Retry Prompt",
+					CustomID: fmt.Sprintf("admin_prompt_page_retry_%d", id),
+				},
+			},
+		},
+	}
+}
+
 func promptListHandler(index, max int, event *events.ComponentInteractionCreate) (components []discord.LayoutComponent) {
 	history, err := database.ListHistory(index)
 
@@ -0,0 +1,199 @@
+package admincommand
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+const (
+	// pullEditInterval keeps the progress edits within the Discord rate limits
+	pullEditInterval = 2 * time.Second
+	pullBarWidth     = 20
+	// interactionLifetime is how long the interaction response can be edited
+	interactionLifetime = 15 * time.Minute
+)
+
+var (
+	// pullJobs holds the cancel functions of the running pulls by interaction id
+	pullJobs   = make(map[string]context.CancelFunc)
+	pullJobsMu sync.Mutex
+)
+
+// pullProgress sums the download progress of the layers of a model
+type pullProgress struct {
+	status  string
+	layers  map[string]ollamaApi.ProgressResponse
+	started time.Time
+}
+
+// startPull pulls the model in the background, the interaction response is
+// edited with the progress until the interaction token expires, the result is
+// posted in the channel.
+func startPull(event *events.ApplicationCommandInteractionCreate, model string, add bool) []discord.LayoutComponent {
+	id := event.ID().String()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pullJobsMu.Lock()
+	pullJobs[id] = cancel
+	pullJobsMu.Unlock()
+
+	progress := &pullProgress{
+		status:  "starting",
+		layers:  make(map[string]ollamaApi.ProgressResponse),
+		started: time.Now(),
+	}
+
+	go func() {
+		defer func() {
+			pullJobsMu.Lock()
+			delete(pullJobs, id)
+			pullJobsMu.Unlock()
+			cancel()
+		}()
+
+		var lastEdit time.Time
+		err := ollama.Client.Pull(ctx, &ollamaApi.PullRequest{Model: model}, func(pr ollamaApi.ProgressResponse) error {
+			progress.update(pr)
+			if time.Since(lastEdit) < pullEditInterval || time.Since(progress.started) > interactionLifetime {
+				return nil
+			}
+			lastEdit = time.Now()
+			util.UpdateInteractionResponse(event, progress.components(model, id))
+			return nil
+		})
+
+		content := fmt.Sprintf("Pulled %s in %s", model, time.Since(progress.started).Round(time.Second))
+		switch {
+		case ctx.Err() != nil && err != nil:
+			content = fmt.Sprintf("Cancelled the pull of %s", model)
+		case err != nil:
+			slog.Error("Error pulling model: ", slog.Any("err", err))
+			content = fmt.Sprintf("Failed to pull %s: %s", model, err)
+		case add:
+			details, err := addModel(model)
+			if err != nil {
+				slog.Error("Error creating model: ", slog.Any("err", err))
+				content += fmt.Sprintf(", but adding it failed: %s", err)
+			} else {
+				content += " and added it to the bot"
+				if modelDetails := details.Details(); modelDetails != "" {
+					content += "\n-# " + modelDetails
+				}
+			}
+		}
+
+		if time.Since(progress.started) < interactionLifetime {
+			util.UpdateInteractionResponse(event, []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: content,
+				},
+			})
+		}
+
+		_, err = event.Client().Rest.CreateMessage(event.Channel().ID(), discord.MessageCreate{
+			Content: fmt.Sprintf("<@%s> %s", event.User().ID, content),
+			AllowedMentions: &discord.AllowedMentions{
+				Users: []snowflake.ID{event.User().ID},
+			},
+		})
+		if err != nil {
+			slog.Error("Error posting the pull result: ", slog.Any("err", err))
+		}
+	}()
+
+	return progress.components(model, id)
+}
+
+// cancelPull cancels a running pull, returns false when the pull already finished
+func cancelPull(id string) bool {
+	pullJobsMu.Lock()
+	defer pullJobsMu.Unlock()
+
+	cancel, ok := pullJobs[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (p *pullProgress) update(pr ollamaApi.ProgressResponse) {
+	p.status = pr.Status
+	if pr.Digest != "" {
+		p.layers[pr.Digest] = pr
+	}
+}
+
+func (p *pullProgress) components(model, id string) []discord.LayoutComponent {
+	var completed, total int64
+	for _, layer := range p.layers {
+		completed += layer.Completed
+		total += layer.Total
+	}
+
+	content := fmt.Sprintf("### Pulling %s\n-# %s", model, p.status)
+	if total > 0 {
+		filled := int(completed * pullBarWidth / total)
+		content = fmt.Sprintf("### Pulling %s\n`%s%s` %d%% · %s / %s\n-# %s",
+			model,
+			strings.Repeat("█", filled),
+			strings.Repeat("░", pullBarWidth-filled),
+			completed*100/total,
+			formatBytes(completed),
+			formatBytes(total),
+			p.status,
+		)
+	}
+
+	return []discord.LayoutComponent{
+		discord.ContainerComponent{
+			Components: []discord.ContainerSubComponent{
+				discord.TextDisplayComponent{
+					Content: content,
+				},
+				discord.ActionRowComponent{
+					Components: []discord.InteractiveComponent{
+						discord.ButtonComponent{
+							Style:    discord.ButtonStyleDanger,
+							Label:    "Cancel",
+							CustomID: fmt.Sprintf("admin_ollama_cancel_%s", id),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// addModel adds a model with the details the backend reports for it
+func addModel(model string) (database.Model, error) {
+	details, err := ollama.ShowModel(context.TODO(), model)
+	if err != nil {
+		return details, err
+	}
+	return details, database.AddModel(details)
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
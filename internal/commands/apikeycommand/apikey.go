@@ -0,0 +1,141 @@
+package apikeycommand
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+var (
+	ApiKeyCmd = ApiKeyCommand{
+		Name:        "apikey",
+		Description: "Manage your keys for the OpenAI compatible api",
+	}
+)
+
+type ApiKeyCommand struct {
+	Name        string
+	Description string
+}
+
+func (a ApiKeyCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	err := event.DeferCreateMessage(true)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	args := event.SlashCommandInteractionData()
+	userID := event.User().ID.String()
+
+	var components []discord.LayoutComponent
+	switch *args.SubCommandName {
+	case "create":
+		key, apiKey, err := database.CreateUserAPIKey(userID, args.String("name"))
+		if err != nil {
+			slog.Error("Error creating api key: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		components = []discord.LayoutComponent{
+			discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### Created key %s\n```\n%s\n```\nThis key is only shown once. Use it as the api key of any OpenAI client with the `/v1` path of the bot as base url, the usage is paid with your coins", apiKey.ID, key),
+					},
+				},
+			},
+		}
+	case "revoke":
+		err := database.RevokeUserAPIKey(userID, args.String("key_id"))
+		if err == sql.ErrNoRows {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("You don't have an active key with id %s", args.String("key_id")),
+				},
+			}
+		} else if err != nil {
+			slog.Error("Error revoking api key: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		} else {
+			components = []discord.LayoutComponent{
+				discord.TextDisplayComponent{
+					Content: "Successfully revoked the key",
+				},
+			}
+		}
+	case "list":
+		apiKeys, err := database.ListUserAPIKeys(userID)
+		if err != nil {
+			slog.Error("Error listing api keys: ", slog.Any("err", err))
+			util.RespondWithError(event, err)
+			return
+		}
+
+		for _, apiKey := range apiKeys {
+			if apiKey.RevokedAt != nil {
+				continue
+			}
+			lastUsed := "never"
+			if apiKey.LastUsedAt != nil {
+				lastUsed = fmt.Sprintf("<t:%d:R>", apiKey.LastUsedAt.Unix())
+			}
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: fmt.Sprintf("### ID: %s\n### Name: %s\n### Last used: %s", apiKey.ID, apiKey.Name, lastUsed),
+					},
+				},
+			})
+		}
+
+		if len(components) == 0 {
+			components = append(components, discord.ContainerComponent{
+				Components: []discord.ContainerSubComponent{
+					discord.TextDisplayComponent{
+						Content: "You don't have any api keys, create one with /apikey create",
+					},
+				},
+			})
+		}
+	}
+	util.UpdateInteractionResponse(event, components)
+}
+
+func (a ApiKeyCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "create",
+			Description: "Create a new api key",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "name",
+					Description: "Name to recognise the key by",
+					Required:    true,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "list",
+			Description: "List your active api keys",
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "revoke",
+			Description: "Revoke one of your api keys",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "key_id",
+					Description: "ID of the key",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,224 @@
+package askcommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+	"github.com/stollenaar/ollamabot/internal/util/retrieval"
+)
+
+var (
+	AskCmd = AskCommand{
+		Name:        "ask",
+		Description: "Ask a question answered from the indexed messages of a channel",
+	}
+)
+
+type AskCommand struct {
+	Name        string
+	Description string
+}
+
+func (a AskCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	if event.GuildID() == nil {
+		respond(event, "Questions can only be asked about server channels")
+		return
+	}
+
+	sub := event.SlashCommandInteractionData()
+	question := sub.String("question")
+	channelID, permissions := event.Channel().ID(), event.Channel().Permissions
+	if channel, ok := sub.OptChannel("channel"); ok {
+		channelID, permissions = channel.ID, channel.Permissions
+	}
+	// the index holds the messages of the channel, so only those who can read them can ask about them
+	if !permissions.Has(discord.PermissionViewChannel, discord.PermissionReadMessageHistory) {
+		respond(event, fmt.Sprintf("You can't read the messages of <#%s>", channelID))
+		return
+	}
+
+	model := util.ConfigFile.DM_DEFAULT_MODEL
+	if name, ok := sub.OptString("model"); ok {
+		model = name
+	}
+	if model == "" {
+		respond(event, "Pick the model answering with the model option")
+		return
+	}
+	if _, err := database.GetModel(model); err != nil {
+		respond(event, fmt.Sprintf("%s is not a model of the bot, check /list for the models", model))
+		return
+	}
+
+	err := database.CheckQuota(event.User().ID.String(), model)
+	if err != nil {
+		content := "Something went wrong while checking your balance"
+		if err == database.ErrInsufficientBalance {
+			content = fmt.Sprintf("You don't have enough coins left to use %s, check /list for the prices", model)
+		} else {
+			slog.Error("Error checking quota: ", slog.Any("err", err))
+		}
+		respond(event, content)
+		return
+	}
+
+	count, err := database.CountChannelChunks(channelID.String())
+	if err != nil {
+		slog.Error("Error counting the index:", slog.Any("err", err))
+	}
+	if count == 0 {
+		respond(event, fmt.Sprintf("<#%s> isn't indexed yet, index it with /index first", channelID))
+		return
+	}
+
+	err = event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	err = database.AddHistory(database.History{
+		ModelName: model,
+		UserID:    event.User().ID.String(),
+		Prompt:    question,
+	})
+	if err != nil {
+		slog.Error("Error saving history: ", slog.Any("err", err))
+	}
+
+	requestID := event.ID().String()
+	ctx, done := ollama.NewRequest(context.Background(), requestID, event.Channel().ID().String(), event.User().ID.String())
+	defer done()
+
+	sources, err := retrieve(ctx, event, channelID.String(), question)
+	if reason := ollama.StopReason(err); reason != "" {
+		updateContent(event, reason)
+		return
+	} else if errors.Is(err, retrieval.ErrOtherModel) {
+		updateContent(event, fmt.Sprintf("<#%s> was indexed with another embedding model, index it again with /index", channelID))
+		return
+	} else if err != nil {
+		slog.Error("Error retrieving messages:", slog.Any("err", err))
+		updateContent(event, fmt.Sprintf("Failed to search the messages of <#%s>", channelID))
+		return
+	}
+
+	stopComponents := util.StopComponents(requestID)
+	release, err := ollama.Queue.Acquire(ctx, model, event.User().ID.String(), func(position int) {
+		content := ollama.QueueMessage(model, position)
+		_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content:    &content,
+			Components: &stopComponents,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+	})
+	if err != nil {
+		updateContent(event, ollama.StopReason(err))
+		return
+	}
+	defer release()
+
+	stream := util.NewInteractionStream(event.Client().Rest, event.ApplicationID(), event.Token(), requestID)
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	req := &ollamaApi.ChatRequest{
+		Model: model,
+		Messages: []ollamaApi.Message{
+			{Role: "system", Content: retrieval.SystemPrompt(sources)},
+			{Role: "user", Content: question},
+		},
+		Options: ollama.Options(model, nil),
+	}
+	usage := ollama.NewUsage(req)
+	err = ollama.Client.Chat(ctx, req, usage.Count(func(cr ollamaApi.ChatResponse) error {
+		if err := stream.Write(cr.Message.Content); err != nil || !cr.Done {
+			return err
+		}
+		stream.Write(retrieval.Citations(sources))
+		return stream.Close()
+	}))
+	usage.Charge(event.User().ID.String(), err)
+	if reason := ollama.StopReason(err); reason != "" {
+		stream.Write("\n\n-# " + reason)
+		stream.Close()
+	} else if err != nil {
+		slog.Error("Error generating response:", slog.Any("err", err))
+		updateContent(event, err.Error())
+	}
+}
+
+func (a AskCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionString{
+			Name:        "question",
+			Description: "The question to answer from the messages",
+			Required:    true,
+		},
+		discord.ApplicationCommandOptionChannel{
+			Name:        "channel",
+			Description: "The indexed channel to answer from, defaults to this channel",
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "model",
+			Description: "The model answering, defaults to the default model of the bot",
+		},
+	}
+}
+
+// retrieve embeds the question and returns the most similar chunks of the
+// channel as sources linking to their first message
+func retrieve(ctx context.Context, event *events.ApplicationCommandInteractionCreate, channelID, question string) (sources []retrieval.Source, err error) {
+	embeddings, err := retrieval.Embed(ctx, event.User().ID.String(), []string{question})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := database.SearchChannelChunks(channelID, ollama.EmbeddingModel(), embeddings[0], retrieval.TopK)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, retrieval.ErrOtherModel
+	}
+	for _, chunk := range chunks {
+		sources = append(sources, retrieval.Source{
+			Content:   chunk.Content,
+			Reference: util.MessageLink(chunk.GuildID, chunk.ChannelID, chunk.MessageID),
+		})
+	}
+	return
+}
+
+// respond answers the command with an ephemeral message
+func respond(event *events.ApplicationCommandInteractionCreate, content string) {
+	err := event.CreateMessage(discord.MessageCreate{
+		Content: content,
+		Flags:   discord.MessageFlagEphemeral,
+	})
+	if err != nil {
+		slog.Error("Error responding: ", slog.Any("err", err))
+	}
+}
+
+// updateContent replaces the deferred response with the content
+func updateContent(event *events.ApplicationCommandInteractionCreate, content string) {
+	_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content:    &content,
+		Components: &[]discord.LayoutComponent{},
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
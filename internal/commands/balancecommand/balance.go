@@ -0,0 +1,79 @@
+package balancecommand
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+var (
+	BalanceCmd = BalanceCommand{
+		Name:        "balance",
+		Description: "Show how many coins you have on each platform",
+	}
+)
+
+type BalanceCommand struct {
+	Name        string
+	Description string
+}
+
+func (b BalanceCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	err := event.DeferCreateMessage(true)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	balances, err := database.ListBalances(event.User().ID.String())
+	if err != nil {
+		slog.Error("Error listing balances: ", slog.Any("err", err))
+		util.RespondWithError(event, err)
+		return
+	}
+
+	platforms, err := database.ListPlatforms()
+	if err != nil {
+		slog.Error("Error listing platforms: ", slog.Any("err", err))
+		util.RespondWithError(event, err)
+		return
+	}
+	names := make(map[string]string)
+	for _, platform := range platforms {
+		names[platform.ID] = platform.Name
+	}
+
+	var components []discord.LayoutComponent
+	for _, balance := range balances {
+		name, ok := names[balance.PlatformID]
+		if !ok {
+			name = balance.PlatformID
+		}
+		components = append(components, discord.ContainerComponent{
+			Components: []discord.ContainerSubComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("### Platform: %s\n### Balance: %d coins", name, balance.Balance),
+				},
+			},
+		})
+	}
+
+	if len(components) == 0 {
+		components = append(components, discord.ContainerComponent{
+			Components: []discord.ContainerSubComponent{
+				discord.TextDisplayComponent{
+					Content: "You don't have any coins yet, use /topup to buy some",
+				},
+			},
+		})
+	}
+	util.UpdateInteractionResponse(event, components)
+}
+
+func (b BalanceCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return nil
+}
@@ -1,15 +1,27 @@
 package commands
 
 import (
+	"log"
+	"log/slog"
 	"reflect"
+	"strings"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/stollenaar/ollamabot/internal/commands/admincommand"
+	"github.com/stollenaar/ollamabot/internal/commands/apikeycommand"
+	"github.com/stollenaar/ollamabot/internal/commands/askcommand"
+	"github.com/stollenaar/ollamabot/internal/commands/balancecommand"
+	"github.com/stollenaar/ollamabot/internal/commands/indexcommand"
+	"github.com/stollenaar/ollamabot/internal/commands/kbcommand"
 	"github.com/stollenaar/ollamabot/internal/commands/listcommand"
+	"github.com/stollenaar/ollamabot/internal/commands/messagecommand"
 	"github.com/stollenaar/ollamabot/internal/commands/promptcommand"
+	"github.com/stollenaar/ollamabot/internal/commands/summarizecommand"
 	"github.com/stollenaar/ollamabot/internal/commands/threadcommand"
+	"github.com/stollenaar/ollamabot/internal/commands/topupcommand"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 type CommandI interface {
@@ -17,12 +29,33 @@ type CommandI interface {
 	CreateCommandArguments() []discord.ApplicationCommandOption
 }
 
+// ContextMenuI is a command used from the Apps menu of a message or a user,
+// its Type field holds which of the two
+type ContextMenuI interface {
+	Handler(e *events.ApplicationCommandInteractionCreate)
+}
+
 var (
 	Commands = []CommandI{
 		admincommand.AdminCmd,
+		apikeycommand.ApiKeyCmd,
+		askcommand.AskCmd,
+		balancecommand.BalanceCmd,
+		indexcommand.IndexCmd,
+		kbcommand.KbCmd,
 		listcommand.ListCmd,
 		promptcommand.PromptCmd,
+		summarizecommand.SummarizeCmd,
 		threadcommand.ThreadCmd,
+		topupcommand.TopupCmd,
+	}
+	// Discord allows at most 5 message and 5 user context menu commands
+	ContextMenus = []ContextMenuI{
+		messagecommand.ExplainCmd,
+		messagecommand.TranslateCmd,
+		messagecommand.RewriteCmd,
+		summarizecommand.SummarizeFromHereCmd,
+		threadcommand.ContinueCmd,
 	}
 	ApplicationCommands []discord.ApplicationCommandCreate
 	CommandHandlers     = make(map[string]func(e *events.ApplicationCommandInteractionCreate))
@@ -32,27 +65,32 @@ var (
 
 func init() {
 	for _, cmd := range Commands {
+		name := reflect.ValueOf(cmd).FieldByName("Name").String()
 		ApplicationCommands = append(ApplicationCommands, discord.SlashCommandCreate{
-			Name:        reflect.ValueOf(cmd).FieldByName("Name").String(),
+			Name:        name,
 			Description: reflect.ValueOf(cmd).FieldByName("Description").String(),
 			Options:     cmd.CreateCommandArguments(),
 		})
-		CommandHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = cmd.Handler
+		CommandHandlers[name] = cmd.Handler
+		registerInteractionHandlers(name, cmd)
+	}
 
-		if _, ok := reflect.TypeOf(cmd).MethodByName("ModalHandler"); ok {
-			ModalSubmitHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(e *events.ModalSubmitInteractionCreate) {
-				reflect.ValueOf(cmd).MethodByName("ModalHandler").Call([]reflect.Value{
-					reflect.ValueOf(e),
-				})
-			}
+	for _, cmd := range ContextMenus {
+		name := reflect.ValueOf(cmd).FieldByName("Name").String()
+		commandType := reflect.ValueOf(cmd).FieldByName("Type")
+		if !commandType.IsValid() {
+			log.Fatalf("Context menu command %s has no Type", name)
 		}
-		if _, ok := reflect.TypeOf(cmd).MethodByName("ComponentHandler"); ok {
-			ComponentHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(e *events.ComponentInteractionCreate) {
-				reflect.ValueOf(cmd).MethodByName("ComponentHandler").Call([]reflect.Value{
-					reflect.ValueOf(e),
-				})
-			}
+		switch commandType.Interface() {
+		case discord.ApplicationCommandTypeMessage:
+			ApplicationCommands = append(ApplicationCommands, discord.MessageCommandCreate{Name: name})
+		case discord.ApplicationCommandTypeUser:
+			ApplicationCommands = append(ApplicationCommands, discord.UserCommandCreate{Name: name})
+		default:
+			log.Fatalf("Context menu command %s has no message or user Type", name)
 		}
+		CommandHandlers[name] = cmd.Handler
+		registerInteractionHandlers(name, cmd)
 	}
 
 	ApplicationCommands = append(ApplicationCommands,
@@ -63,6 +101,27 @@ func init() {
 	)
 
 	CommandHandlers["ping"] = PingCommand
+	ComponentHandlers["stop"] = StopButton
+}
+
+// registerInteractionHandlers routes the modals and components with custom
+// IDs starting with the name to the ModalHandler and ComponentHandler of the
+// command, when it has them
+func registerInteractionHandlers(name string, cmd any) {
+	if _, ok := reflect.TypeOf(cmd).MethodByName("ModalHandler"); ok {
+		ModalSubmitHandlers[name] = func(e *events.ModalSubmitInteractionCreate) {
+			reflect.ValueOf(cmd).MethodByName("ModalHandler").Call([]reflect.Value{
+				reflect.ValueOf(e),
+			})
+		}
+	}
+	if _, ok := reflect.TypeOf(cmd).MethodByName("ComponentHandler"); ok {
+		ComponentHandlers[name] = func(e *events.ComponentInteractionCreate) {
+			reflect.ValueOf(cmd).MethodByName("ComponentHandler").Call([]reflect.Value{
+				reflect.ValueOf(e),
+			})
+		}
+	}
 }
 
 // PingCommand sends back the pong
@@ -72,3 +131,28 @@ func PingCommand(event *events.ApplicationCommandInteractionCreate) {
 		Flags:   util.ConfigFile.SetEphemeral(),
 	})
 }
+
+// StopButton stops the answer the Stop button belongs to
+func StopButton(event *events.ComponentInteractionCreate) {
+	_, id, _ := strings.Cut(event.Data.CustomID(), "_")
+	stopped, err := ollama.Stop(id, event.User().ID.String())
+
+	if stopped {
+		if err := event.DeferUpdateMessage(); err != nil {
+			slog.Error("Error acknowledging the stop:", slog.Any("err", err))
+		}
+		return
+	}
+
+	content := "This answer already finished"
+	if err != nil {
+		content = err.Error()
+	}
+	err = event.CreateMessage(discord.MessageCreate{
+		Content: content,
+		Flags:   util.ConfigFile.SetEphemeral(),
+	})
+	if err != nil {
+		slog.Error("Error responding to the stop:", slog.Any("err", err))
+	}
+}
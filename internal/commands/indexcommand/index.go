@@ -0,0 +1,188 @@
+package indexcommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/omit"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+	"github.com/stollenaar/ollamabot/internal/util/retrieval"
+)
+
+const (
+	defaultMessages = 1000
+	maxMessages     = 10000
+	// chunkLength is how many characters of messages are embedded together
+	chunkLength = 1500
+)
+
+var (
+	IndexCmd = IndexCommand{
+		Name:        "index",
+		Description: "Index the messages of a channel so /ask can answer from them",
+	}
+)
+
+type IndexCommand struct {
+	Name        string
+	Description string
+}
+
+func (i IndexCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	if event.GuildID() == nil {
+		event.CreateMessage(discord.MessageCreate{
+			Content: "Channels can only be indexed in a server",
+			Flags:   discord.MessageFlagEphemeral,
+		})
+		return
+	}
+
+	sub := event.SlashCommandInteractionData()
+	channelID, permissions := event.Channel().ID(), event.Channel().Permissions
+	if channel, ok := sub.OptChannel("channel"); ok {
+		channelID, permissions = channel.ID, channel.Permissions
+	}
+	if !permissions.Has(discord.PermissionManageMessages) && event.User().ID.String() != util.ConfigFile.ADMIN_USER_ID {
+		event.CreateMessage(discord.MessageCreate{
+			Content: fmt.Sprintf("You need the Manage Messages permission in <#%s> to index it", channelID),
+			Flags:   discord.MessageFlagEphemeral,
+		})
+		return
+	}
+
+	limit := defaultMessages
+	if messages, ok := sub.OptInt("messages"); ok {
+		limit = messages
+	}
+
+	err := event.DeferCreateMessage(true)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	ctx, done := ollama.NewRequest(context.Background(), event.ID().String(), event.Channel().ID().String(), event.User().ID.String())
+	defer done()
+
+	messages, err := util.FetchMessages(ctx, event.Client().Rest, channelID, limit, time.Time{}, time.Time{}, func(read int) {
+		util.UpdateInteractionResponse(event, []discord.LayoutComponent{
+			discord.TextDisplayComponent{Content: fmt.Sprintf("Read %d messages of <#%s>…", read, channelID)},
+		})
+	})
+	if reason := ollama.StopReason(err); reason != "" {
+		util.RespondWithError(event, errors.New(reason))
+		return
+	} else if err != nil {
+		slog.Error("Error fetching messages:", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("failed to read the messages of <#%s>, check that the bot can see the channel", channelID))
+		return
+	}
+
+	chunks := chunkMessages(event.GuildID().String(), channelID.String(), messages)
+	if len(chunks) == 0 {
+		util.RespondWithError(event, fmt.Errorf("<#%s> has no messages with text to index", channelID))
+		return
+	}
+	util.UpdateInteractionResponse(event, []discord.LayoutComponent{
+		discord.TextDisplayComponent{Content: fmt.Sprintf("Embedding %d messages of <#%s>…", len(messages), channelID)},
+	})
+
+	inputs := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		inputs[i] = chunk.Content
+	}
+	embeddings, err := retrieval.Embed(ctx, event.User().ID.String(), inputs)
+	if reason := ollama.StopReason(err); reason != "" {
+		util.RespondWithError(event, errors.New(reason))
+		return
+	} else if err != nil {
+		slog.Error("Error embedding messages:", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("failed to embed the messages with %s", ollama.EmbeddingModel()))
+		return
+	}
+	for i := range chunks {
+		chunks[i].Embedding = embeddings[i]
+	}
+
+	if err := database.ReplaceChannelChunks(channelID.String(), chunks); err != nil {
+		slog.Error("Error saving the index:", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("failed to save the index of <#%s>", channelID))
+		return
+	}
+
+	util.UpdateInteractionResponse(event, []discord.LayoutComponent{
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("Indexed %d messages of <#%s> in %d chunks, use /ask to ask about them", len(messages), channelID, len(chunks)),
+		},
+	})
+}
+
+func (i IndexCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionChannel{
+			Name:        "channel",
+			Description: "The channel to index, defaults to this channel",
+			ChannelTypes: []discord.ChannelType{
+				discord.ChannelTypeGuildText,
+				discord.ChannelTypeGuildNews,
+				discord.ChannelTypeGuildPublicThread,
+				discord.ChannelTypeGuildPrivateThread,
+				discord.ChannelTypeGuildNewsThread,
+			},
+		},
+		discord.ApplicationCommandOptionInt{
+			Name:        "messages",
+			Description: fmt.Sprintf("How many of the latest messages to index, defaults to %d", defaultMessages),
+			MinValue:    omit.Ptr(1),
+			MaxValue:    omit.Ptr(maxMessages),
+		},
+	}
+}
+
+// chunkMessages groups consecutive messages into chunks of about chunkLength
+// characters, messages longer than that are split over several chunks
+func chunkMessages(guildID, channelID string, messages []discord.Message) (chunks []database.ChannelChunk) {
+	var lines []string
+	var first discord.Message
+	length := 0
+	flush := func() {
+		if len(lines) == 0 {
+			return
+		}
+		chunks = append(chunks, database.ChannelChunk{
+			MessageID: first.ID.String(),
+			GuildID:   guildID,
+			ChannelID: channelID,
+			Content:   strings.Join(lines, "\n"),
+			ModelName: ollama.EmbeddingModel(),
+			CreatedAt: first.CreatedAt,
+		})
+		lines, length = nil, 0
+	}
+
+	for _, message := range messages {
+		if strings.TrimSpace(message.Content) == "" {
+			continue
+		}
+		for _, part := range util.BreakContent(util.TranscriptLine(message), chunkLength) {
+			if length+len(part) > chunkLength {
+				flush()
+			}
+			if len(lines) == 0 {
+				first = message
+			}
+			lines = append(lines, part)
+			length += len(part) + 1
+		}
+	}
+	flush()
+	return
+}
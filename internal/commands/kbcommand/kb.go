@@ -0,0 +1,128 @@
+package kbcommand
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+	"github.com/stollenaar/ollamabot/internal/util/retrieval"
+)
+
+// nameRegex limits knowledge base names to what reads well in a select menu
+var nameRegex = regexp.MustCompile(`^[\w .-]{1,64}$`)
+
+var (
+	KbCmd = KbCommand{
+		Name:        "kb",
+		Description: "Manage the knowledge bases threads can be grounded in",
+	}
+)
+
+type KbCommand struct {
+	Name        string
+	Description string
+}
+
+func (k KbCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	sub := event.SlashCommandInteractionData()
+	switch *sub.SubCommandName {
+	case "add":
+		addHandler(sub, event)
+	}
+}
+
+func (k KbCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "add",
+			Description: "Add a document to a knowledge base, creating the knowledge base when it's new",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "name",
+					Description: "Name of the knowledge base",
+					Required:    true,
+				},
+				discord.ApplicationCommandOptionAttachment{
+					Name:        "file",
+					Description: "A text file like .md, .txt or source code, export PDFs to text first",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// addHandler chunks and embeds the uploaded file into the knowledge base
+func addHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	name := strings.TrimSpace(args.String("name"))
+	if !nameRegex.MatchString(name) {
+		event.CreateMessage(discord.MessageCreate{
+			Content: "Knowledge base names are up to 64 letters, numbers, spaces, dots, dashes and underscores",
+			Flags:   discord.MessageFlagEphemeral,
+		})
+		return
+	}
+	attachment, _ := args.OptAttachment("file")
+
+	err := event.DeferCreateMessage(true)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	content, err := util.FetchTextAttachment(attachment)
+	if errors.Is(err, util.ErrNotText) {
+		util.RespondWithError(event, fmt.Errorf("%s doesn't hold text, upload text files like .md, .txt or source code and export PDFs to text first", attachment.Filename))
+		return
+	} else if errors.Is(err, util.ErrDocumentTooLarge) {
+		util.RespondWithError(event, fmt.Errorf("%s is too large, %w", attachment.Filename, err))
+		return
+	} else if err != nil {
+		slog.Error("Error fetching the document:", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("failed to download %s", attachment.Filename))
+		return
+	}
+
+	ctx, done := ollama.NewRequest(context.Background(), event.ID().String(), event.Channel().ID().String(), event.User().ID.String())
+	defer done()
+
+	document := database.KnowledgeDocument{
+		KnowledgeBase: name,
+		FileName:      attachment.Filename,
+		Content:       content,
+		AddedBy:       event.User().ID.String(),
+	}
+	chunks, err := retrieval.EmbedDocuments(ctx, event.User().ID.String(), document)
+	if reason := ollama.StopReason(err); reason != "" {
+		util.RespondWithError(event, errors.New(reason))
+		return
+	} else if err != nil {
+		slog.Error("Error embedding the document:", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("failed to embed %s with %s", attachment.Filename, ollama.EmbeddingModel()))
+		return
+	}
+	if len(chunks) == 0 {
+		util.RespondWithError(event, fmt.Errorf("%s is empty", attachment.Filename))
+		return
+	}
+
+	if err := database.AddKnowledgeDocument(document, chunks); err != nil {
+		slog.Error("Error saving the document:", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("failed to save %s", attachment.Filename))
+		return
+	}
+
+	util.UpdateInteractionResponse(event, []discord.LayoutComponent{
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("Added %s to the knowledge base **%s** in %d chunks, pick it when creating a /thread", attachment.Filename, name, len(chunks)),
+		},
+	})
+}
@@ -44,15 +44,29 @@ func (l ListCommand) Handler(event *events.ApplicationCommandInteractionCreate)
 		return
 	}
 
-	for model, platforms := range models {
+	details, err := database.ListModelDetails()
+	if err != nil {
+		slog.Error("Error fetching model details: ", slog.Any("err", err))
+	}
+
+	for _, model := range details {
+		platforms, ok := models[model.Name]
+		if !ok {
+			continue
+		}
+
 		var costs []string
 		for _, platform := range platforms {
 			costs = append(costs, fmt.Sprintf("### Platform: %s\n### Cost: %d/token", platform.PlatformName, platform.Tokens))
 		}
+		content := fmt.Sprintf("### Name: %s\n%s", model.Name, strings.Join(costs, "\n"))
+		if modelDetails := model.Details(); modelDetails != "" {
+			content += "\n-# " + modelDetails
+		}
 		container := discord.ContainerComponent{
 			Components: []discord.ContainerSubComponent{
 				discord.TextDisplayComponent{
-					Content: fmt.Sprintf("### Name: %s\n%s", model, strings.Join(costs, "\n")),
+					Content: content,
 				},
 			},
 		}
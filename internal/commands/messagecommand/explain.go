@@ -0,0 +1,35 @@
+package messagecommand
+
+import (
+	"fmt"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+const explainPrompt = "Explain the Discord message the user sends so anyone can follow it. " +
+	"Cover what it means, the context it assumes and any jargon, abbreviations, code or references in it. Keep it short."
+
+var (
+	ExplainCmd = ExplainCommand{
+		Name: "Explain",
+		Type: discord.ApplicationCommandTypeMessage,
+	}
+)
+
+type ExplainCommand struct {
+	Name string
+	Type discord.ApplicationCommandType
+}
+
+// Handler explains the message with CONTEXT_MENU_MODEL
+func (e ExplainCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	message := event.MessageCommandInteractionData().TargetMessage()
+	if !hasText(event, message) {
+		return
+	}
+
+	heading := fmt.Sprintf("-# Explaining %s", messageLink(event, message.ID.String()))
+	answer(event, heading, explainPrompt, util.TranscriptLine(message))
+}
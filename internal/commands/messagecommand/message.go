@@ -0,0 +1,159 @@
+package messagecommand
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+// interaction is what the context menu and modal submit events share to
+// answer with
+type interaction interface {
+	discord.Interaction
+	Client() *bot.Client
+	CreateMessage(messageCreate discord.MessageCreate, opts ...rest.RequestOpt) error
+	DeferCreateMessage(ephemeral bool, opts ...rest.RequestOpt) error
+}
+
+// answer streams the answer of CONTEXT_MENU_MODEL to the prompt as the
+// response to the interaction, below the heading
+func answer(event interaction, heading, system, prompt string) {
+	model := util.ConfigFile.CONTEXT_MENU_MODEL
+	if model == "" {
+		respond(event, "The bot has no model to answer with, ask the admin to set CONTEXT_MENU_MODEL")
+		return
+	}
+	models, err := database.ListChatModels()
+	if err != nil {
+		slog.Error("Error listing models: ", slog.Any("err", err))
+		respond(event, "Something went wrong while checking the model")
+		return
+	}
+	if !slices.ContainsFunc(models, func(m database.Model) bool { return m.Name == model }) {
+		respond(event, fmt.Sprintf("The model %s can't answer anymore, ask the admin to set CONTEXT_MENU_MODEL", model))
+		return
+	}
+
+	err = database.CheckQuota(event.User().ID.String(), model)
+	if err != nil {
+		content := "Something went wrong while checking your balance"
+		if err == database.ErrInsufficientBalance {
+			content = fmt.Sprintf("You don't have enough coins left to use %s, check /list for the prices", model)
+		} else {
+			slog.Error("Error checking quota: ", slog.Any("err", err))
+		}
+		respond(event, content)
+		return
+	}
+
+	err = event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	err = database.AddHistory(database.History{
+		ModelName: model,
+		UserID:    event.User().ID.String(),
+		Prompt:    prompt,
+	})
+	if err != nil {
+		slog.Error("Error saving history: ", slog.Any("err", err))
+	}
+
+	requestID := event.ID().String()
+	ctx, done := ollama.NewRequest(context.Background(), requestID, event.Channel().ID().String(), event.User().ID.String())
+	defer done()
+
+	release, err := ollama.Queue.Acquire(ctx, model, event.User().ID.String(), func(position int) {
+		updateContent(event, ollama.QueueMessage(model, position), util.StopComponents(requestID))
+	})
+	if err != nil {
+		updateContent(event, ollama.StopReason(err), nil)
+		return
+	}
+	defer release()
+
+	stream := util.NewInteractionStream(event.Client().Rest, event.ApplicationID(), event.Token(), requestID)
+	stream.Write(heading + "\n")
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	req := &ollamaApi.ChatRequest{
+		Model: model,
+		Messages: []ollamaApi.Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+		Options: ollama.Options(model),
+	}
+	usage := ollama.NewUsage(req)
+	err = ollama.Client.Chat(ctx, req, usage.Count(func(cr ollamaApi.ChatResponse) error {
+		if err := stream.Write(cr.Message.Content); err != nil || !cr.Done {
+			return err
+		}
+		return stream.Close()
+	}))
+	usage.Charge(event.User().ID.String(), err)
+	if reason := ollama.StopReason(err); reason != "" {
+		stream.Write("\n\n-# " + reason)
+		stream.Close()
+	} else if err != nil {
+		slog.Error("Error generating response:", slog.Any("err", err))
+		updateContent(event, err.Error(), nil)
+	}
+}
+
+// messageLink links to the message in the channel of the interaction
+func messageLink(event interaction, messageID string) string {
+	guildID := "@me"
+	if event.GuildID() != nil {
+		guildID = event.GuildID().String()
+	}
+	return util.MessageLink(guildID, event.Channel().ID().String(), messageID)
+}
+
+// hasText reports if the message has text to work with, responding when it doesn't
+func hasText(event interaction, message discord.Message) bool {
+	if strings.TrimSpace(message.Content) == "" {
+		respond(event, "This message has no text")
+		return false
+	}
+	return true
+}
+
+// respond answers the interaction with an ephemeral message
+func respond(event interaction, content string) {
+	err := event.CreateMessage(discord.MessageCreate{
+		Content: content,
+		Flags:   discord.MessageFlagEphemeral,
+	})
+	if err != nil {
+		slog.Error("Error responding: ", slog.Any("err", err))
+	}
+}
+
+// updateContent replaces the deferred response with the content and components
+func updateContent(event interaction, content string, components []discord.LayoutComponent) {
+	if components == nil {
+		components = []discord.LayoutComponent{}
+	}
+	_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content:    &content,
+		Components: &components,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,119 @@
+package messagecommand
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+const rewritePrompt = "Rewrite the text the user sends %s. " +
+	"Keep the meaning, the language and the formatting like Markdown, mentions and emoji. Answer with only the rewritten text."
+
+// tones are the rewrites offered in the select menu, with how the model is asked for them
+var tones = []struct {
+	Label       string
+	Instruction string
+}{
+	{"More formal", "in a more formal tone"},
+	{"More casual", "in a more casual tone"},
+	{"Friendlier", "in a friendlier and warmer tone"},
+	{"More professional", "in a professional tone fit for work"},
+	{"More concise", "more concisely, dropping what isn't needed"},
+	{"Simpler", "in simpler words that are easy to follow"},
+	{"More confident", "in a more confident and assertive tone"},
+	{"Fix grammar", "with the spelling and grammar fixed, changing nothing else"},
+}
+
+var (
+	RewriteCmd = RewriteCommand{
+		Name: "Rewrite",
+		Type: discord.ApplicationCommandTypeMessage,
+	}
+)
+
+type RewriteCommand struct {
+	Name string
+	Type discord.ApplicationCommandType
+}
+
+// Handler asks how to rewrite the message
+func (r RewriteCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	message := event.MessageCommandInteractionData().TargetMessage()
+	if !hasText(event, message) {
+		return
+	}
+
+	var options []discord.StringSelectMenuOption
+	for i, tone := range tones {
+		options = append(options, discord.StringSelectMenuOption{
+			Label: tone.Label,
+			Value: fmt.Sprint(i),
+		})
+	}
+
+	err := event.Modal(discord.ModalCreate{
+		CustomID: fmt.Sprintf("%s_%s", r.Name, message.ID),
+		Title:    "Rewrite message",
+		Components: []discord.LayoutComponent{
+			discord.LabelComponent{
+				Label: "Tone",
+				Component: discord.StringSelectMenuComponent{
+					CustomID:  "tone",
+					MinValues: new(int),
+					Options:   options,
+					Required:  false,
+				},
+			},
+			discord.LabelComponent{
+				Label:       "Instructions",
+				Description: "How to rewrite it instead of or on top of the tone",
+				Component: discord.TextInputComponent{
+					CustomID:    "instructions",
+					Style:       discord.TextInputStyleShort,
+					Placeholder: "as a pirate",
+					MaxLength:   200,
+					Required:    false,
+				},
+			},
+			discord.LabelComponent{
+				Label: "Text",
+				Component: discord.TextInputComponent{
+					CustomID: "text",
+					Style:    discord.TextInputStyleParagraph,
+					Value:    message.Content,
+					Required: true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Error creating modal: ", slog.Any("err", err))
+	}
+}
+
+// ModalHandler rewrites the text in the picked tone
+func (r RewriteCommand) ModalHandler(event *events.ModalSubmitInteractionCreate) {
+	submittedData := util.ExtractModalSubmitData(event.Data.AllComponents())
+
+	var instructions, labels []string
+	for i, tone := range tones {
+		if submittedData["tone"] == fmt.Sprint(i) {
+			instructions, labels = append(instructions, tone.Instruction), append(labels, strings.ToLower(tone.Label))
+		}
+	}
+	if custom := strings.TrimSpace(submittedData["instructions"]); custom != "" {
+		instructions, labels = append(instructions, custom), append(labels, custom)
+	}
+	if len(instructions) == 0 {
+		respond(event, "Pick a tone or write how to rewrite the message")
+		return
+	}
+
+	_, messageID, _ := strings.Cut(event.Data.CustomID, "_")
+	heading := fmt.Sprintf("-# Rewrite of %s %s", messageLink(event, messageID), strings.Join(labels, ", "))
+	answer(event, heading, fmt.Sprintf(rewritePrompt, strings.Join(instructions, " and ")), submittedData["text"])
+}
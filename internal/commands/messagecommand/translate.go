@@ -0,0 +1,111 @@
+package messagecommand
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+const translatePrompt = "Translate the text the user sends to %s. " +
+	"Keep the meaning, the tone and the formatting like Markdown, mentions and emoji. Answer with only the translation."
+
+// languages are offered in the select menu, any other can be typed in
+var languages = []string{
+	"English", "Spanish", "French", "German", "Italian", "Portuguese", "Dutch", "Polish",
+	"Swedish", "Russian", "Ukrainian", "Turkish", "Greek", "Arabic", "Hindi", "Indonesian",
+	"Vietnamese", "Thai", "Chinese", "Japanese", "Korean",
+}
+
+var (
+	TranslateCmd = TranslateCommand{
+		Name: "Translate",
+		Type: discord.ApplicationCommandTypeMessage,
+	}
+)
+
+type TranslateCommand struct {
+	Name string
+	Type discord.ApplicationCommandType
+}
+
+// Handler asks which language to translate the message to
+func (t TranslateCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	message := event.MessageCommandInteractionData().TargetMessage()
+	if !hasText(event, message) {
+		return
+	}
+
+	err := event.Modal(discord.ModalCreate{
+		CustomID: fmt.Sprintf("%s_%s", t.Name, message.ID),
+		Title:    "Translate message",
+		Components: []discord.LayoutComponent{
+			discord.LabelComponent{
+				Label: "Language",
+				Component: discord.StringSelectMenuComponent{
+					CustomID:  "language",
+					MinValues: new(int),
+					Options:   languageOptions(event.Locale()),
+					Required:  false,
+				},
+			},
+			discord.LabelComponent{
+				Label:       "Other language",
+				Description: "A language that isn't in the list, this wins over the one picked",
+				Component: discord.TextInputComponent{
+					CustomID:    "other",
+					Style:       discord.TextInputStyleShort,
+					Placeholder: "Brazilian Portuguese",
+					MaxLength:   100,
+					Required:    false,
+				},
+			},
+			discord.LabelComponent{
+				Label: "Text",
+				Component: discord.TextInputComponent{
+					CustomID: "text",
+					Style:    discord.TextInputStyleParagraph,
+					Value:    message.Content,
+					Required: true,
+				},
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("Error creating modal: ", slog.Any("err", err))
+	}
+}
+
+// ModalHandler translates the text to the picked language
+func (t TranslateCommand) ModalHandler(event *events.ModalSubmitInteractionCreate) {
+	submittedData := util.ExtractModalSubmitData(event.Data.AllComponents())
+
+	language := strings.TrimSpace(submittedData["other"])
+	if language == "" {
+		language = submittedData["language"]
+	}
+	if language == "" {
+		respond(event, "Pick a language to translate to")
+		return
+	}
+
+	_, messageID, _ := strings.Cut(event.Data.CustomID, "_")
+	heading := fmt.Sprintf("-# Translation of %s to %s", messageLink(event, messageID), language)
+	answer(event, heading, fmt.Sprintf(translatePrompt, language), submittedData["text"])
+}
+
+// languageOptions lists the languages to pick from, the language of the
+// Discord client is picked by default
+func languageOptions(locale discord.Locale) (options []discord.StringSelectMenuOption) {
+	for _, language := range languages {
+		options = append(options, discord.StringSelectMenuOption{
+			Label:   language,
+			Value:   language,
+			Default: strings.HasPrefix(locale.String(), language),
+		})
+	}
+	return
+}
@@ -2,17 +2,17 @@ package promptcommand
 
 import (
 	"context"
-	"iter"
-	"log"
+	"fmt"
 	"log/slog"
-	"maps"
-	"slices"
+	"sync"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/omit"
 	ollamaApi "github.com/ollama/ollama/api"
 	"github.com/stollenaar/ollamabot/internal/database"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 var (
@@ -20,7 +20,6 @@ var (
 		Name:        "prompt",
 		Description: "Prompt command to query ollama",
 	}
-	OllamaClient *ollamaApi.Client
 )
 
 type PromptCommand struct {
@@ -28,16 +27,15 @@ type PromptCommand struct {
 	Description string
 }
 
-func init() {
-	client, err := ollamaApi.ClientFromEnvironment()
-	if err != nil {
-		log.Fatal(err)
-	}
-	OllamaClient = client
-}
+var (
+	// pendingOptions holds the options of the /prompt arguments by user
+	// until the modal is submitted
+	pendingOptions   = make(map[string]map[string]any)
+	pendingOptionsMu sync.Mutex
+)
 
 func (p PromptCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
-	models, err := database.ListPlatformModels()
+	models, err := database.ListChatModels()
 
 	if err != nil {
 		slog.Error("Error fetching models: ", slog.Any("err", err))
@@ -57,6 +55,21 @@ func (p PromptCommand) Handler(event *events.ApplicationCommandInteractionCreate
 		return
 	}
 
+	options, err := promptOptions(event.SlashCommandInteractionData())
+	if err != nil {
+		err = event.CreateMessage(discord.MessageCreate{
+			Content: fmt.Sprintf("Invalid options: %s", err),
+			Flags:   discord.MessageFlagEphemeral,
+		})
+		if err != nil {
+			slog.Error("Error responding: ", slog.Any("err", err))
+		}
+		return
+	}
+	pendingOptionsMu.Lock()
+	pendingOptions[event.User().ID.String()] = options
+	pendingOptionsMu.Unlock()
+
 	err = event.Modal(discord.ModalCreate{
 		CustomID: "prompt",
 		Title:    "Submit Prompt",
@@ -65,7 +78,7 @@ func (p PromptCommand) Handler(event *events.ApplicationCommandInteractionCreate
 				Label: "Select Model",
 				Component: discord.StringSelectMenuComponent{
 					CustomID: "model",
-					Options:  modelsToOptions(slices.Collect(maps.Keys(models))),
+					Options:  ollama.ModelsToOptions(models),
 				},
 			},
 			discord.LabelComponent{
@@ -101,19 +114,44 @@ func (p PromptCommand) Handler(event *events.ApplicationCommandInteractionCreate
 }
 
 func (p PromptCommand) ModalHandler(event *events.ModalSubmitInteractionCreate) {
-	err := event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+	submittedData := util.ExtractModalSubmitData(event.Data.AllComponents())
 
-	if err != nil {
-		slog.Error("Error deferring: ", slog.Any("err", err))
-		return
-	}
+	pendingOptionsMu.Lock()
+	options := pendingOptions[event.User().ID.String()]
+	delete(pendingOptions, event.User().ID.String())
+	pendingOptionsMu.Unlock()
 
-	submittedData := extractModalSubmitData(event.Data.AllComponents())
 	slog.Info("Received prompt submission",
 		slog.String("model", submittedData["model"]),
 		slog.String("prompt", submittedData["prompt"]),
 	)
 
+	err := database.CheckQuota(event.User().ID.String(), submittedData["model"])
+	if err != nil {
+		content := "Something went wrong while checking your balance"
+		if err == database.ErrInsufficientBalance {
+			content = fmt.Sprintf("You don't have enough coins left to use %s, check /list for the prices", submittedData["model"])
+		} else {
+			slog.Error("Error checking quota: ", slog.Any("err", err))
+		}
+
+		err = event.CreateMessage(discord.MessageCreate{
+			Content: content,
+			Flags:   discord.MessageFlagEphemeral,
+		})
+		if err != nil {
+			slog.Error("Error responding: ", slog.Any("err", err))
+		}
+		return
+	}
+
+	err = event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
 	err = database.AddHistory(database.History{
 		ModelName: submittedData["model"],
 		UserID:    event.User().ID.String(),
@@ -124,69 +162,145 @@ func (p PromptCommand) ModalHandler(event *events.ModalSubmitInteractionCreate)
 		slog.Error("Error saving history: ", slog.Any("err", err))
 	}
 
-	var ollamaContext []int32
-	if submittedData["context"] == "current_context" {
-		ollamaContext = database.GetContext(event.User().ID.String(), submittedData["model"])
+	conversationID := fmt.Sprintf("prompt_%s", event.User().ID)
+	if submittedData["context"] == "new_context" {
+		err = database.ClearMessages(conversationID)
+		if err != nil {
+			slog.Error("Error clearing conversation:", slog.Any("err", err))
+		}
+	}
+
+	history, err := database.GetMessages(conversationID)
+	if err != nil {
+		slog.Error("Error fetching conversation:", slog.Any("err", err))
 	}
 
-	OllamaClient.Generate(context.TODO(), &ollamaApi.GenerateRequest{
-		Model:   submittedData["model"],
-		Prompt:  submittedData["prompt"],
-		Stream:  new(bool),
-		Context: util.Int32ToIntSlice(ollamaContext),
-	}, func(gr ollamaApi.GenerateResponse) error {
-		// Getting around the 4096 word limit
-		contents := util.BreakContent(gr.Response, 4096)
+	userMessage := database.Message{
+		ConversationID: conversationID,
+		Role:           "user",
+		Content:        submittedData["prompt"],
+		ModelName:      submittedData["model"],
+	}
 
-		var embeds []discord.Embed
-		for _, content := range contents {
-			embeds = append(embeds, discord.Embed{
-				Description: content,
-			})
-		}
+	requestID := event.ID().String()
+	ctx, done := ollama.NewRequest(context.Background(), requestID, event.Channel().ID().String(), event.User().ID.String())
+	defer done()
 
+	stopComponents := util.StopComponents(requestID)
+	release, err := ollama.Queue.Acquire(ctx, submittedData["model"], event.User().ID.String(), func(position int) {
+		content := ollama.QueueMessage(submittedData["model"], position)
+		_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content:    &content,
+			Components: &stopComponents,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+	})
+	if err != nil {
+		content := ollama.StopReason(err)
 		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-			Embeds: &embeds,
+			Content:    &content,
+			Components: &[]discord.LayoutComponent{},
 		})
 		if err != nil {
-			slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", gr.Response))
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+	defer release()
+
+	stream := util.NewInteractionStream(event.Client().Rest, event.ApplicationID(), event.Token(), requestID)
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	req := &ollamaApi.ChatRequest{
+		Model:    submittedData["model"],
+		Messages: ollama.ChatMessages(append(history, userMessage)),
+		Options:  ollama.Options(submittedData["model"], options),
+	}
+	usage := ollama.NewUsage(req)
+	err = ollama.Client.Chat(ctx, req, usage.Count(func(cr ollamaApi.ChatResponse) error {
+		if err := stream.Write(cr.Message.Content); err != nil || !cr.Done {
+			return err
+		}
+		if err := stream.Close(); err != nil {
+			return err
 		}
 
-		err = database.SetContext(database.UserContext{
-			UserID:    event.User().ID.String(),
-			ModelName: submittedData["model"],
-			Context:   util.IntToInt32Slice(gr.Context),
+		err := database.AddMessages(userMessage, database.Message{
+			ConversationID: conversationID,
+			Role:           "assistant",
+			Content:        stream.String(),
+			ModelName:      submittedData["model"],
 		})
 		if err != nil {
-			slog.Error("Error updating context:", slog.Any("err", err))
+			slog.Error("Error updating conversation:", slog.Any("err", err))
 		}
-		return err
-	})
+		// the answer is already shown, failing to save it doesn't replace it
+		return nil
+	}))
+	usage.Charge(event.User().ID.String(), err)
+	if reason := ollama.StopReason(err); reason != "" {
+		stream.Write("\n\n-# " + reason)
+		stream.Close()
+	} else if err != nil {
+		slog.Error("Error generating response:", slog.Any("err", err))
+		content := err.Error()
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content:    &content,
+			Components: &[]discord.LayoutComponent{},
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+	}
 }
 
 func (p PromptCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
-	return nil
-}
-
-func modelsToOptions(models []string) (options []discord.StringSelectMenuOption) {
-	for _, model := range models {
-		options = append(options, discord.StringSelectMenuOption{
-			Label: model,
-			Value: model,
-		})
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionFloat{
+			Name:        "temperature",
+			Description: "Higher values answer more creatively",
+			MinValue:    omit.Ptr(0.0),
+		},
+		discord.ApplicationCommandOptionInt{
+			Name:        "num_ctx",
+			Description: "Size of the context window in tokens",
+			MinValue:    omit.Ptr(1),
+		},
+		discord.ApplicationCommandOptionFloat{
+			Name:        "top_p",
+			Description: "Only sample from the tokens making up this probability",
+			MinValue:    omit.Ptr(0.0),
+			MaxValue:    omit.Ptr(1.0),
+		},
+		discord.ApplicationCommandOptionInt{
+			Name:        "seed",
+			Description: "Seed for reproducible answers",
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "stop",
+			Description: "Stop sequences as quoted strings, like \"User:\" \"###\"",
+		},
 	}
-	return
 }
 
-func extractModalSubmitData(components iter.Seq[discord.Component]) map[string]string {
-	formData := make(map[string]string)
-	for component := range components {
-		switch c := component.(type) {
-		case discord.TextInputComponent:
-			formData[c.CustomID] = c.Value
-		case discord.StringSelectMenuComponent:
-			formData[c.CustomID] = c.Values[0]
+// promptOptions validates the generation options given as arguments
+func promptOptions(args discord.SlashCommandInteractionData) (map[string]any, error) {
+	params := make(map[string][]string)
+	for _, name := range []string{"temperature", "num_ctx", "top_p", "seed"} {
+		if option, ok := args.Options[name]; ok {
+			params[name] = []string{string(option.Value)}
+		}
+	}
+	if stop, ok := args.OptString("stop"); ok {
+		stops, err := ollama.SplitFields(stop)
+		if err != nil {
+			return nil, err
 		}
+		params["stop"] = stops
 	}
-	return formData
+	return ollamaApi.FormatParams(params)
 }
@@ -0,0 +1,393 @@
+package summarizecommand
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/omit"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+const (
+	defaultMessages = 100
+	maxMessages     = 1000
+	// reservedTokens is the part of the context window kept for the
+	// instructions and the answer
+	reservedTokens = 1024
+	// charsPerToken is a low estimate of the characters in a token, so
+	// batches rather end up too small than overflow the context
+	charsPerToken = 3
+	// maxRounds bounds how often notes are merged before the final summary
+	maxRounds = 3
+)
+
+const (
+	notesPrompt = "You take notes on a part of a Discord conversation for a summary. " +
+		"Write short bullet points on what was discussed naming who said it, every decision that was made and every question left open. " +
+		"Only use what is in the conversation."
+	mergePrompt = "You merge notes taken on consecutive parts of a Discord conversation into one set of notes. " +
+		"Keep who said what, every decision and every open question, drop what is repeated."
+	summaryPrompt = "You summarize a Discord conversation. Answer in Markdown with exactly these sections:\n" +
+		"### Summary\nA few sentences on what was discussed.\n" +
+		"### Participants\nA bullet per participant with what they contributed.\n" +
+		"### Decisions\nA bullet per decision that was made, or None.\n" +
+		"### Open questions\nA bullet per question left open, or None.\n" +
+		"Only use what is in the conversation."
+)
+
+var (
+	SummarizeCmd = SummarizeCommand{
+		Name:        "summarize",
+		Description: "Summarize the latest messages of this channel",
+	}
+)
+
+type SummarizeCommand struct {
+	Name        string
+	Description string
+}
+
+var (
+	SummarizeFromHereCmd = SummarizeFromHereCommand{
+		Name: "Summarize from here",
+		Type: discord.ApplicationCommandTypeMessage,
+	}
+)
+
+type SummarizeFromHereCommand struct {
+	Name string
+	Type discord.ApplicationCommandType
+}
+
+func (s SummarizeCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	sub := event.SlashCommandInteractionData()
+
+	var since, until time.Time
+	var err error
+	if value, ok := sub.OptString("since"); ok {
+		if since, err = parseTime(value); err != nil {
+			respond(event, err.Error())
+			return
+		}
+	}
+	if value, ok := sub.OptString("until"); ok {
+		if until, err = parseTime(value); err != nil {
+			respond(event, err.Error())
+			return
+		}
+	}
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		respond(event, "The since time has to be before the until time")
+		return
+	}
+
+	// a time range reads all of its messages unless a count is given
+	limit := defaultMessages
+	if messages, ok := sub.OptInt("messages"); ok {
+		limit = messages
+	} else if !since.IsZero() {
+		limit = maxMessages
+	}
+
+	model, _ := sub.OptString("model")
+	summarize(event, model, func(ctx context.Context, progress func(read int)) ([]discord.Message, error) {
+		return util.FetchMessages(ctx, event.Client().Rest, event.Channel().ID(), limit, since, until, progress)
+	})
+}
+
+func (s SummarizeCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionInt{
+			Name:        "messages",
+			Description: fmt.Sprintf("How many of the latest messages to summarize, defaults to %d", defaultMessages),
+			MinValue:    omit.Ptr(1),
+			MaxValue:    omit.Ptr(maxMessages),
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "since",
+			Description: "Summarize the messages since a time ago like 2h or 3d, or a date like 2025-01-31",
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "until",
+			Description: "Summarize the messages until a time ago like 2h or 3d, or a date like 2025-01-31",
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "model",
+			Description: "The model summarizing, defaults to the default model of the bot",
+		},
+	}
+}
+
+// Handler summarizes the channel from the message it was used on, reading
+// at most maxMessages messages onwards from it
+func (s SummarizeFromHereCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	target := event.MessageCommandInteractionData().TargetMessage()
+	summarize(event, "", func(ctx context.Context, progress func(read int)) ([]discord.Message, error) {
+		messages, err := util.FetchMessagesAfter(ctx, event.Client().Rest, event.Channel().ID(), target.ID, maxMessages-1, progress)
+		return append([]discord.Message{target}, messages...), err
+	})
+}
+
+// fetcher reads the messages to summarize oldest first, progress is told how
+// many messages were read so far
+type fetcher func(ctx context.Context, progress func(read int)) ([]discord.Message, error)
+
+// summarize reads the messages of the channel with fetch and answers with
+// their summary. Conversations that don't fit the context window of the
+// model are split in batches, each batch is summarized to notes and the
+// notes are merged until they fit.
+func summarize(event *events.ApplicationCommandInteractionCreate, model string, fetch fetcher) {
+	if event.GuildID() == nil {
+		respond(event, "Only server channels can be summarized")
+		return
+	}
+	channelID := event.Channel().ID()
+	if !event.Channel().Permissions.Has(discord.PermissionViewChannel, discord.PermissionReadMessageHistory) {
+		respond(event, fmt.Sprintf("You can't read the messages of <#%s>", channelID))
+		return
+	}
+
+	if model == "" {
+		model = util.ConfigFile.DM_DEFAULT_MODEL
+	}
+	if model == "" {
+		respond(event, "Pick the model summarizing with the model option")
+		return
+	}
+	if _, err := database.GetModel(model); err != nil {
+		respond(event, fmt.Sprintf("%s is not a model of the bot, check /list for the models", model))
+		return
+	}
+
+	err := database.CheckQuota(event.User().ID.String(), model)
+	if err != nil {
+		content := "Something went wrong while checking your balance"
+		if err == database.ErrInsufficientBalance {
+			content = fmt.Sprintf("You don't have enough coins left to use %s, check /list for the prices", model)
+		} else {
+			slog.Error("Error checking quota: ", slog.Any("err", err))
+		}
+		respond(event, content)
+		return
+	}
+
+	err = event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	requestID := event.ID().String()
+	ctx, done := ollama.NewRequest(context.Background(), requestID, channelID.String(), event.User().ID.String())
+	defer done()
+
+	messages, err := fetch(ctx, func(read int) {
+		updateContent(event, fmt.Sprintf("Read %d messages…", read), util.StopComponents(requestID))
+	})
+	if reason := ollama.StopReason(err); reason != "" {
+		updateContent(event, reason, nil)
+		return
+	} else if err != nil {
+		slog.Error("Error fetching messages:", slog.Any("err", err))
+		updateContent(event, fmt.Sprintf("Failed to read the messages of <#%s>, check that the bot can see the channel", channelID), nil)
+		return
+	}
+
+	var lines []string
+	for _, message := range messages {
+		if strings.TrimSpace(message.Content) != "" {
+			lines = append(lines, util.TranscriptLine(message))
+		}
+	}
+	if len(lines) == 0 {
+		updateContent(event, "There are no messages with text to summarize", nil)
+		return
+	}
+
+	err = database.AddHistory(database.History{
+		ModelName: model,
+		UserID:    event.User().ID.String(),
+		Prompt:    fmt.Sprintf("Summarize %d messages of <#%s>", len(messages), channelID),
+	})
+	if err != nil {
+		slog.Error("Error saving history: ", slog.Any("err", err))
+	}
+
+	// one slot is held for all the batches so the summary isn't interleaved with other requests
+	release, err := ollama.Queue.Acquire(ctx, model, event.User().ID.String(), func(position int) {
+		updateContent(event, ollama.QueueMessage(model, position), util.StopComponents(requestID))
+	})
+	if err != nil {
+		updateContent(event, ollama.StopReason(err), nil)
+		return
+	}
+	defer release()
+
+	tokens := 0
+	defer func() {
+		if tokens == 0 {
+			return
+		}
+		if _, err := database.ChargeUsage(event.User().ID.String(), model, tokens); err != nil {
+			slog.Error("Error charging usage:", slog.Any("err", err))
+		}
+	}()
+
+	options := ollama.Options(model)
+	limitChars := max((ollama.ContextWindow(model, options)-reservedTokens)*charsPerToken, 2000)
+
+	parts, prompt, heading := batch(lines, "\n", limitChars), notesPrompt, "Conversation:"
+	for round := 0; len(parts) > 1 && round < maxRounds; round++ {
+		var notes []string
+		for i, part := range parts {
+			updateContent(event, fmt.Sprintf("Summarizing part %d of %d…", i+1, len(parts)), util.StopComponents(requestID))
+
+			note, used, err := complete(ctx, model, options, prompt, heading+"\n"+part)
+			tokens += used
+			if reason := ollama.StopReason(err); reason != "" {
+				updateContent(event, reason, nil)
+				return
+			} else if err != nil {
+				slog.Error("Error summarizing messages:", slog.Any("err", err))
+				updateContent(event, err.Error(), nil)
+				return
+			}
+			notes = append(notes, note)
+		}
+		parts, prompt, heading = batch(notes, "\n\n", limitChars), mergePrompt, "Notes on the conversation:"
+	}
+
+	stream := util.NewInteractionStream(event.Client().Rest, event.ApplicationID(), event.Token(), requestID)
+	stream.Write(fmt.Sprintf("-# Summary of %d messages from <t:%d:f> to <t:%d:f>\n", len(messages), messages[0].CreatedAt.Unix(), messages[len(messages)-1].CreatedAt.Unix()))
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	req := &ollamaApi.ChatRequest{
+		Model: model,
+		Messages: []ollamaApi.Message{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: heading + "\n" + strings.Join(parts, "\n\n")},
+		},
+		Options: options,
+	}
+	usage := ollama.NewUsage(req)
+	err = ollama.Client.Chat(ctx, req, usage.Count(func(cr ollamaApi.ChatResponse) error {
+		if err := stream.Write(cr.Message.Content); err != nil || !cr.Done {
+			return err
+		}
+		return stream.Close()
+	}))
+	tokens += usage.Tokens(err)
+	if reason := ollama.StopReason(err); reason != "" {
+		stream.Write("\n\n-# " + reason)
+		stream.Close()
+	} else if err != nil {
+		slog.Error("Error generating response:", slog.Any("err", err))
+		updateContent(event, err.Error(), nil)
+	}
+}
+
+// complete returns the answer of the model without streaming it, with the
+// tokens it took
+func complete(ctx context.Context, model string, options map[string]any, system, content string) (answer string, tokens int, err error) {
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	var stream bool
+	req := &ollamaApi.ChatRequest{
+		Model: model,
+		Messages: []ollamaApi.Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: content},
+		},
+		Stream:  &stream,
+		Options: options,
+	}
+	usage := ollama.NewUsage(req)
+	err = ollama.Client.Chat(ctx, req, usage.Count(func(cr ollamaApi.ChatResponse) error {
+		answer += cr.Message.Content
+		return nil
+	}))
+	return answer, usage.Tokens(err), err
+}
+
+// batch joins the texts with the separator into batches of at most limit
+// characters, texts longer than that are split over several batches
+func batch(texts []string, separator string, limit int) (batches []string) {
+	var current strings.Builder
+	for _, text := range texts {
+		for _, part := range util.BreakContent(text, limit) {
+			if current.Len() > 0 && current.Len()+len(separator)+len(part) > limit {
+				batches = append(batches, current.String())
+				current.Reset()
+			}
+			if current.Len() > 0 {
+				current.WriteString(separator)
+			}
+			current.WriteString(part)
+		}
+	}
+	if current.Len() > 0 {
+		batches = append(batches, current.String())
+	}
+	return
+}
+
+// parseTime reads a time ago like 90m, 2h, 3d or 1w, or a date in UTC like
+// 2025-01-31 or 2025-01-31 18:00
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.DateOnly, "2006-01-02 15:04", time.DateTime} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+
+	ago, err := time.ParseDuration(value)
+	if number, ok := strings.CutSuffix(value, "d"); ok {
+		days, dayErr := strconv.Atoi(number)
+		ago, err = time.Duration(days)*24*time.Hour, dayErr
+	} else if number, ok := strings.CutSuffix(value, "w"); ok {
+		weeks, weekErr := strconv.Atoi(number)
+		ago, err = time.Duration(weeks)*7*24*time.Hour, weekErr
+	}
+	if err != nil || ago <= 0 {
+		return time.Time{}, fmt.Errorf("invalid time %s, use a time ago like 2h or 3d, or a date like 2025-01-31", value)
+	}
+	return time.Now().Add(-ago), nil
+}
+
+// respond answers the command with an ephemeral message
+func respond(event *events.ApplicationCommandInteractionCreate, content string) {
+	err := event.CreateMessage(discord.MessageCreate{
+		Content: content,
+		Flags:   discord.MessageFlagEphemeral,
+	})
+	if err != nil {
+		slog.Error("Error responding: ", slog.Any("err", err))
+	}
+}
+
+// updateContent replaces the deferred response with the content and components
+func updateContent(event *events.ApplicationCommandInteractionCreate, content string, components []discord.LayoutComponent) {
+	if components == nil {
+		components = []discord.LayoutComponent{}
+	}
+	_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content:    &content,
+		Components: &components,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
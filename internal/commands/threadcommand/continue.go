@@ -0,0 +1,141 @@
+package threadcommand
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+var (
+	ContinueCmd = ContinueCommand{
+		Name: "Continue in thread",
+		Type: discord.ApplicationCommandTypeMessage,
+	}
+)
+
+type ContinueCommand struct {
+	Name string
+	Type discord.ApplicationCommandType
+}
+
+// Handler opens the /thread form for a thread on the message, titled with
+// its first line
+func (c ContinueCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	message := event.MessageCommandInteractionData().TargetMessage()
+	if event.GuildID() == nil {
+		respond(event, "Threads can only be started in a server")
+		return
+	}
+	if strings.TrimSpace(message.Content) == "" {
+		respond(event, "This message has no text to continue from")
+		return
+	}
+
+	title, _, _ := strings.Cut(strings.TrimSpace(message.Content), "\n")
+	if runes := []rune(title); len(runes) > 100 {
+		title = string(runes[:100])
+	}
+
+	components, err := modalComponents(title)
+	if err != nil {
+		slog.Error("Error fetching models: ", slog.Any("err", err))
+		respondModelsError(event)
+		return
+	}
+
+	err = event.Modal(discord.ModalCreate{
+		CustomID:   fmt.Sprintf("%s_%s", c.Name, message.ID),
+		Title:      "Continue in LLM Thread",
+		Components: components,
+	})
+	if err != nil {
+		slog.Error("Error creating modal: ", slog.Any("err", err))
+	}
+}
+
+// ModalHandler starts the thread on the message, the conversation in it
+// begins with the message
+func (c ContinueCommand) ModalHandler(event *events.ModalSubmitInteractionCreate) {
+	submittedData := util.ExtractModalSubmitData(event.Data.AllComponents())
+
+	options, err := ollama.ParseOptions(submittedData["options"])
+	if err != nil {
+		respond(event, fmt.Sprintf("Invalid options: %s", err))
+		return
+	}
+
+	_, id, _ := strings.Cut(event.Data.CustomID, "_")
+	messageID, err := snowflake.Parse(id)
+	if err != nil {
+		slog.Error("Error parsing the message id: ", slog.Any("err", err))
+		respond(event, "Something went wrong while finding the message")
+		return
+	}
+	message, err := event.Client().Rest.GetMessage(event.Channel().ID(), messageID)
+	if err != nil {
+		slog.Error("Error fetching the message: ", slog.Any("err", err))
+		respond(event, "The message can't be found anymore")
+		return
+	}
+
+	thread, err := event.Client().Rest.CreateThreadFromMessage(event.Channel().ID(), messageID, discord.ThreadCreateFromMessage{
+		Name:                submittedData["title"],
+		AutoArchiveDuration: discord.AutoArchiveDuration24h,
+	})
+	if err != nil {
+		slog.Error("Error creating thread: ", slog.Any("err", err))
+		respond(event, "Failed to start a thread on this message, it may already have one or be in a thread")
+		return
+	}
+
+	err = database.AddThread(submittedData["model"], submittedData["system"], thread.ID().String(), options, submittedData["knowledge_base"])
+	if err != nil {
+		slog.Error("Error saving thread info: ", slog.Any("err", err))
+		respond(event, "Something went wrong while saving the thread")
+		return
+	}
+
+	err = database.AddMessages(database.Message{
+		ConversationID: thread.ID().String(),
+		Role:           "user",
+		Content:        util.TranscriptLine(*message),
+		ModelName:      submittedData["model"],
+	})
+	if err != nil {
+		slog.Error("Error saving the message: ", slog.Any("err", err))
+	}
+
+	_, err = event.Client().Rest.CreateMessage(thread.ID(), discord.MessageCreate{
+		Content: fmt.Sprintf("-# Continuing from %s with %s, reply here to talk about it",
+			util.MessageLink(event.GuildID().String(), event.Channel().ID().String(), messageID.String()), submittedData["model"]),
+	})
+	if err != nil {
+		slog.Error("Error sending the first message: ", slog.Any("err", err))
+	}
+
+	respond(event, fmt.Sprintf("Continuing in <#%s>", thread.ID()))
+}
+
+// responder is an interaction that can be answered with a message
+type responder interface {
+	CreateMessage(messageCreate discord.MessageCreate, opts ...rest.RequestOpt) error
+}
+
+// respond answers the interaction with an ephemeral message
+func respond(event responder, content string) {
+	err := event.CreateMessage(discord.MessageCreate{
+		Content: content,
+		Flags:   discord.MessageFlagEphemeral,
+	})
+	if err != nil {
+		slog.Error("Error responding: ", slog.Any("err", err))
+	}
+}
@@ -1,17 +1,14 @@
 package threadcommand
 
 import (
-	"iter"
-	"log"
+	"fmt"
 	"log/slog"
-	"maps"
-	"slices"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
-	ollamaApi "github.com/ollama/ollama/api"
 	"github.com/stollenaar/ollamabot/internal/database"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
 var (
@@ -19,7 +16,6 @@ var (
 		Name:        "thread",
 		Description: "spawn a thread for a contained conversation",
 	}
-	OllamaClient *ollamaApi.Client
 )
 
 type ThreadCommand struct {
@@ -27,63 +23,19 @@ type ThreadCommand struct {
 	Description string
 }
 
-func init() {
-	client, err := ollamaApi.ClientFromEnvironment()
-	if err != nil {
-		log.Fatal(err)
-	}
-	OllamaClient = client
-}
-
 func (t ThreadCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
-	models, err := database.ListPlatformModels()
+	components, err := modalComponents("")
 
 	if err != nil {
 		slog.Error("Error fetching models: ", slog.Any("err", err))
-
-		err := event.CreateMessage(discord.MessageCreate{
-			Flags: util.ConfigFile.SetEphemeral() | discord.MessageFlagIsComponentsV2,
-			Components: []discord.LayoutComponent{
-				discord.TextDisplayComponent{
-					Content: "error fetching models",
-				},
-			},
-		})
-
-		if err != nil {
-			slog.Error("Error deferring: ", slog.Any("err", err))
-		}
+		respondModelsError(event)
 		return
 	}
 
 	err = event.Modal(discord.ModalCreate{
-		CustomID: "thread",
-		Title:    "Create LLM Thread",
-		Components: []discord.LayoutComponent{
-			discord.LabelComponent{
-				Label: "Select Model",
-				Component: discord.StringSelectMenuComponent{
-					CustomID: "model",
-					Options:  modelsToOptions(slices.Collect(maps.Keys(models))),
-				},
-			},
-			discord.LabelComponent{
-				Label: "Title",
-				Component: discord.TextInputComponent{
-					CustomID: "title",
-					Style:    discord.TextInputStyleShort,
-					Required: true,
-				},
-			},
-			discord.LabelComponent{
-				Label: "System prompt",
-				Component: discord.TextInputComponent{
-					CustomID: "system",
-					Style:    discord.TextInputStyleParagraph,
-					Required: true,
-				},
-			},
-		},
+		CustomID:   "thread",
+		Title:      "Create LLM Thread",
+		Components: components,
 	})
 	if err != nil {
 		slog.Error("Error creating modal: ", slog.Any("err", err))
@@ -91,9 +43,21 @@ func (t ThreadCommand) Handler(event *events.ApplicationCommandInteractionCreate
 }
 
 func (t ThreadCommand) ModalHandler(event *events.ModalSubmitInteractionCreate) {
-	event.DeferUpdateMessage()
+	submittedData := util.ExtractModalSubmitData(event.Data.AllComponents())
+
+	options, err := ollama.ParseOptions(submittedData["options"])
+	if err != nil {
+		err = event.CreateMessage(discord.MessageCreate{
+			Content: fmt.Sprintf("Invalid options: %s", err),
+			Flags:   discord.MessageFlagEphemeral,
+		})
+		if err != nil {
+			slog.Error("Error responding: ", slog.Any("err", err))
+		}
+		return
+	}
 
-	submittedData := extractModalSubmitData(event.Data.AllComponents())
+	event.DeferUpdateMessage()
 	slog.Info("Received model submission",
 		slog.String("model", submittedData["model"]),
 		slog.String("system", submittedData["system"]),
@@ -110,7 +74,7 @@ func (t ThreadCommand) ModalHandler(event *events.ModalSubmitInteractionCreate)
 		return
 	}
 
-	err = database.AddThread(submittedData["model"], submittedData["system"], thread.ID().String())
+	err = database.AddThread(submittedData["model"], submittedData["system"], thread.ID().String(), options, submittedData["knowledge_base"])
 
 	if err != nil {
 		slog.Error("Error saving thread info: ", slog.Any("err", err))
@@ -122,25 +86,94 @@ func (t ThreadCommand) CreateCommandArguments() []discord.ApplicationCommandOpti
 	return nil
 }
 
-func modelsToOptions(models []string) (options []discord.StringSelectMenuOption) {
-	for _, model := range models {
-		options = append(options, discord.StringSelectMenuOption{
-			Label: model,
-			Value: model,
+// modalComponents builds the form creating a thread, with the title filled in
+func modalComponents(title string) ([]discord.LayoutComponent, error) {
+	models, err := database.ListChatModels()
+	if err != nil {
+		return nil, err
+	}
+
+	components := []discord.LayoutComponent{
+		discord.LabelComponent{
+			Label: "Select Model",
+			Component: discord.StringSelectMenuComponent{
+				CustomID: "model",
+				Options:  ollama.ModelsToOptions(models),
+			},
+		},
+		discord.LabelComponent{
+			Label: "Title",
+			Component: discord.TextInputComponent{
+				CustomID:  "title",
+				Style:     discord.TextInputStyleShort,
+				MaxLength: 100,
+				Required:  true,
+				Value:     title,
+			},
+		},
+		discord.LabelComponent{
+			Label: "System prompt",
+			Component: discord.TextInputComponent{
+				CustomID: "system",
+				Style:    discord.TextInputStyleParagraph,
+				Required: true,
+			},
+		},
+		discord.LabelComponent{
+			Label:       "Options",
+			Description: "Generation options overriding the model defaults, like temperature=0.7 num_ctx=8192",
+			Component: discord.TextInputComponent{
+				CustomID: "options",
+				Style:    discord.TextInputStyleShort,
+				Required: false,
+			},
+		},
+	}
+
+	knowledgeBases, err := database.ListKnowledgeBases()
+	if err != nil {
+		slog.Error("Error fetching knowledge bases: ", slog.Any("err", err))
+	}
+	if len(knowledgeBases) > 0 {
+		components = append(components, discord.LabelComponent{
+			Label:       "Knowledge base",
+			Description: "Ground every reply in the thread in a knowledge base",
+			Component: discord.StringSelectMenuComponent{
+				CustomID:  "knowledge_base",
+				MinValues: new(int),
+				Options:   knowledgeBasesToOptions(knowledgeBases),
+				Required:  false,
+			},
 		})
 	}
-	return
+	return components, nil
 }
 
-func extractModalSubmitData(components iter.Seq[discord.Component]) map[string]string {
-	formData := make(map[string]string)
-	for component := range components {
-		switch c := component.(type) {
-		case discord.TextInputComponent:
-			formData[c.CustomID] = c.Value
-		case discord.StringSelectMenuComponent:
-			formData[c.CustomID] = c.Values[0]
-		}
+// respondModelsError tells the models for the form couldn't be fetched
+func respondModelsError(event *events.ApplicationCommandInteractionCreate) {
+	err := event.CreateMessage(discord.MessageCreate{
+		Flags: util.ConfigFile.SetEphemeral() | discord.MessageFlagIsComponentsV2,
+		Components: []discord.LayoutComponent{
+			discord.TextDisplayComponent{
+				Content: "error fetching models",
+			},
+		},
+	})
+
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
 	}
-	return formData
+}
+
+// knowledgeBasesToOptions lists the knowledge bases to pick from, a select
+// menu holds at most 25 options
+func knowledgeBasesToOptions(knowledgeBases []database.KnowledgeBase) (options []discord.StringSelectMenuOption) {
+	for _, knowledgeBase := range knowledgeBases[:min(len(knowledgeBases), 25)] {
+		options = append(options, discord.StringSelectMenuOption{
+			Label:       knowledgeBase.Name,
+			Value:       knowledgeBase.Name,
+			Description: fmt.Sprintf("%d documents", knowledgeBase.Documents),
+		})
+	}
+	return
 }
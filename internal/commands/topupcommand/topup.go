@@ -0,0 +1,83 @@
+package topupcommand
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/omit"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+var (
+	TopupCmd = TopupCommand{
+		Name:        "topup",
+		Description: "Buy coins on a platform",
+	}
+)
+
+type TopupCommand struct {
+	Name        string
+	Description string
+}
+
+func (t TopupCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+	err := event.DeferCreateMessage(true)
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	args := event.SlashCommandInteractionData()
+
+	platform, err := database.GetPlatform(args.String("platform"))
+	if err != nil {
+		slog.Error("Error fetching platform: ", slog.Any("err", err))
+		util.RespondWithError(event, fmt.Errorf("platform %s doesn't exist, use /list to see the platforms", args.String("platform")))
+		return
+	}
+
+	amount := args.Int("amount")
+	id, err := database.AddTransaction(database.Transaction{
+		UserID:     event.User().ID.String(),
+		PlatformID: platform.ID,
+		Amount:     amount,
+		Date:       time.Now(),
+		Status:     database.TransactionPending,
+	})
+	if err != nil {
+		slog.Error("Error creating transaction: ", slog.Any("err", err))
+		util.RespondWithError(event, err)
+		return
+	}
+
+	components := []discord.LayoutComponent{
+		discord.ContainerComponent{
+			Components: []discord.ContainerSubComponent{
+				discord.TextDisplayComponent{
+					Content: fmt.Sprintf("### Transaction: %s\n### Platform: %s\n### Amount: %d\nYou'll receive %d coins once %s approves the transaction", id, platform.Name, amount, amount*platform.BuyingPower, platform.Name),
+				},
+			},
+		},
+	}
+	util.UpdateInteractionResponse(event, components)
+}
+
+func (t TopupCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionString{
+			Name:        "platform",
+			Description: "ID of the platform to buy coins on",
+			Required:    true,
+		},
+		discord.ApplicationCommandOptionInt{
+			Name:        "amount",
+			Description: "Amount to pay on the platform",
+			Required:    true,
+			MinValue:    omit.Ptr(1),
+		},
+	}
+}
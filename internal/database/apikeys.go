@@ -0,0 +1,173 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefix marks the keys issued by the bot so they are easy to recognise in configs
+const apiKeyPrefix = "obk"
+
+// ErrInvalidAPIKey is returned when a key is malformed, unknown or revoked
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// APIKey is an issued key, the secret part is only known when it's created.
+// Platform keys manage the trades of their platform, user keys spend the
+// coins of their user.
+type APIKey struct {
+	ID         string     `json:"id"`
+	PlatformID string     `json:"platform_id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// CreateAPIKey issues a new key for a platform. The returned key is shown
+// once, only a hash of its secret is stored.
+func CreateAPIKey(platformID, name string) (key string, apiKey APIKey, err error) {
+	return createAPIKey(APIKey{PlatformID: platformID, Name: name})
+}
+
+// CreateUserAPIKey issues a new key spending the coins of a user
+func CreateUserAPIKey(userID, name string) (key string, apiKey APIKey, err error) {
+	return createAPIKey(APIKey{UserID: userID, Name: name})
+}
+
+func createAPIKey(apiKey APIKey) (key string, _ APIKey, err error) {
+	id, err := randomHex(6)
+	if err != nil {
+		return "", apiKey, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", apiKey, err
+	}
+
+	apiKey.ID = id
+	apiKey.CreatedAt = time.Now()
+	_, err = duckdbClient.Exec(`
+		INSERT INTO api_keys (id, platform_id, user_id, name, key_hash, created_at)
+		VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?);
+	`, apiKey.ID, apiKey.PlatformID, apiKey.UserID, apiKey.Name, hashSecret(secret), apiKey.CreatedAt)
+	if err != nil {
+		return "", apiKey, err
+	}
+	return strings.Join([]string{apiKeyPrefix, id, secret}, "_"), apiKey, nil
+}
+
+// AuthenticateAPIKey returns the key matching a full api key and marks it as used
+func AuthenticateAPIKey(key string) (APIKey, error) {
+	parts := strings.Split(key, "_")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	var apiKey APIKey
+	var hash string
+	err := duckdbClient.QueryRow(`
+		SELECT id, COALESCE(platform_id, ''), COALESCE(user_id, ''), COALESCE(name, ''), key_hash, created_at
+		FROM api_keys
+		WHERE id = ? AND revoked_at IS NULL;
+	`, parts[1]).Scan(&apiKey.ID, &apiKey.PlatformID, &apiKey.UserID, &apiKey.Name, &hash, &apiKey.CreatedAt)
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrInvalidAPIKey
+	} else if err != nil {
+		return APIKey{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(hashSecret(parts[2]))) != 1 {
+		return APIKey{}, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	apiKey.LastUsedAt = &now
+	_, err = duckdbClient.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?;`, now, apiKey.ID)
+	return apiKey, err
+}
+
+// RevokeAPIKey revokes a key by its id, returning sql.ErrNoRows when there's no active key with that id
+func RevokeAPIKey(id string) error {
+	return revokeAPIKey(id, "")
+}
+
+// RevokeUserAPIKey revokes a key only when it belongs to the user
+func RevokeUserAPIKey(userID, id string) error {
+	return revokeAPIKey(id, userID)
+}
+
+func revokeAPIKey(id, userID string) error {
+	result, err := duckdbClient.Exec(`
+		UPDATE api_keys SET revoked_at = ?
+		WHERE id = ? AND revoked_at IS NULL AND (? = '' OR user_id = ?);
+	`, time.Now(), id, userID, userID)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListAPIKeys returns the keys of a platform, or every key when platformID is empty
+func ListAPIKeys(platformID string) (apiKeys []APIKey, err error) {
+	return listAPIKeys(`? = '' OR platform_id = ?`, platformID, platformID)
+}
+
+// ListUserAPIKeys returns the keys of a user
+func ListUserAPIKeys(userID string) (apiKeys []APIKey, err error) {
+	return listAPIKeys(`user_id = ?`, userID)
+}
+
+func listAPIKeys(filter string, args ...any) (apiKeys []APIKey, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT id, COALESCE(platform_id, ''), COALESCE(user_id, ''), COALESCE(name, ''), created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE `+filter+`
+		ORDER BY platform_id, user_id, created_at;
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var apiKey APIKey
+		var lastUsedAt, revokedAt sql.NullTime
+		err = rows.Scan(&apiKey.ID, &apiKey.PlatformID, &apiKey.UserID, &apiKey.Name, &apiKey.CreatedAt, &lastUsedAt, &revokedAt)
+		if err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			apiKey.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			apiKey.RevokedAt = &revokedAt.Time
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+	return apiKeys, rows.Err()
+}
+
+// hashSecret hashes the secret part of a key. The secrets are random, so a
+// plain SHA-256 is enough and keeps the lookup on every request cheap.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
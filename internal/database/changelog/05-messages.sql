@@ -0,0 +1,30 @@
+CREATE SEQUENCE seq_messages START 1;
+
+CREATE TABLE IF NOT EXISTS messages (
+    id INTEGER PRIMARY KEY DEFAULT NEXTVAL('seq_messages'),
+    conversation_id VARCHAR NOT NULL,
+    role VARCHAR NOT NULL,
+    content VARCHAR,
+    model_name VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_conversation ON messages (conversation_id);
+
+INSERT INTO
+    messages (conversation_id, role, content, model_name)
+SELECT
+    thread_id,
+    'system',
+    system_prompt,
+    model_name
+FROM
+    threads
+WHERE
+    system_prompt IS NOT NULL
+    AND system_prompt <> '';
+
+ALTER TABLE
+    threads DROP COLUMN context;
+
+DROP TABLE IF EXISTS contexts;
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS dm_settings (
+    user_id VARCHAR PRIMARY KEY,
+    model_name VARCHAR REFERENCES models(name),
+    system_prompt VARCHAR
+);
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_history_user ON history (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user ON transactions (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_platform ON transactions (platform_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS user_balances (
+    user_id VARCHAR,
+    platform_id VARCHAR REFERENCES platforms(id),
+    balance INTEGER DEFAULT 0,
+    PRIMARY KEY (user_id, platform_id)
+);
@@ -0,0 +1,57 @@
+CREATE SEQUENCE seq_ledger_entries START 1;
+
+CREATE SEQUENCE seq_ledger_transfers START 1;
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id INTEGER PRIMARY KEY DEFAULT NEXTVAL('seq_ledger_entries'),
+    transfer_id INTEGER NOT NULL,
+    account VARCHAR NOT NULL,
+    platform_id VARCHAR NOT NULL,
+    amount INTEGER NOT NULL,
+    reference VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_account ON ledger_entries (account, platform_id);
+
+-- Carry the existing balances over as one balanced opening transfer
+INSERT INTO
+    ledger_entries (transfer_id, account, platform_id, amount, reference)
+SELECT
+    0,
+    'user:' || user_id,
+    platform_id,
+    balance,
+    'opening balance'
+FROM
+    user_balances
+WHERE
+    balance <> 0;
+
+INSERT INTO
+    ledger_entries (transfer_id, account, platform_id, amount, reference)
+SELECT
+    0,
+    'opening',
+    platform_id,
+    - balance,
+    'opening balance'
+FROM
+    user_balances
+WHERE
+    balance <> 0;
+
+DROP TABLE user_balances;
+
+CREATE VIEW user_balances AS
+SELECT
+    substr(account, 6) AS user_id,
+    platform_id,
+    CAST(SUM(amount) AS INTEGER) AS balance
+FROM
+    ledger_entries
+WHERE
+    account LIKE 'user:%'
+GROUP BY
+    account,
+    platform_id;
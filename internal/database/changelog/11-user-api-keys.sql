@@ -0,0 +1,6 @@
+ALTER TABLE
+    api_keys
+ADD
+    COLUMN IF NOT EXISTS user_id VARCHAR;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys (user_id);
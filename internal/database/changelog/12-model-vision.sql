@@ -0,0 +1,4 @@
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS vision BOOLEAN DEFAULT FALSE;
@@ -0,0 +1,34 @@
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS family VARCHAR;
+
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS parameter_size VARCHAR;
+
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS quantization VARCHAR;
+
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS context_length INTEGER;
+
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS tools BOOLEAN DEFAULT FALSE;
+
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS embedding BOOLEAN DEFAULT FALSE;
+
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS thinking BOOLEAN DEFAULT FALSE;
@@ -0,0 +1,9 @@
+ALTER TABLE
+    models
+ADD
+    COLUMN IF NOT EXISTS options VARCHAR;
+
+ALTER TABLE
+    threads
+ADD
+    COLUMN options VARCHAR;
@@ -0,0 +1,14 @@
+ALTER TABLE
+    messages
+ADD
+    COLUMN IF NOT EXISTS tool_calls VARCHAR;
+
+ALTER TABLE
+    messages
+ADD
+    COLUMN IF NOT EXISTS tool_name VARCHAR;
+
+ALTER TABLE
+    threads
+ADD
+    COLUMN tools BOOLEAN DEFAULT TRUE;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS channel_chunks (
+    message_id VARCHAR NOT NULL,
+    guild_id VARCHAR NOT NULL,
+    channel_id VARCHAR NOT NULL,
+    content VARCHAR NOT NULL,
+    model_name VARCHAR NOT NULL,
+    embedding FLOAT[] NOT NULL,
+    created_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_channel_chunks_channel ON channel_chunks (channel_id);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS knowledge_bases (
+    name VARCHAR PRIMARY KEY,
+    created_by VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS knowledge_documents (
+    knowledge_base VARCHAR NOT NULL,
+    file_name VARCHAR NOT NULL,
+    content VARCHAR NOT NULL,
+    added_by VARCHAR,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (knowledge_base, file_name)
+);
+
+CREATE TABLE IF NOT EXISTS knowledge_chunks (
+    knowledge_base VARCHAR NOT NULL,
+    file_name VARCHAR NOT NULL,
+    content VARCHAR NOT NULL,
+    model_name VARCHAR NOT NULL,
+    embedding FLOAT[] NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_base ON knowledge_chunks (knowledge_base);
+
+ALTER TABLE
+    threads
+ADD
+    COLUMN knowledge_base VARCHAR;
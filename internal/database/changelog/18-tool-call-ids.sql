@@ -0,0 +1,4 @@
+ALTER TABLE
+    messages
+ADD
+    COLUMN IF NOT EXISTS tool_call_id VARCHAR;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS transactions;
+
+DROP TABLE IF EXISTS platform_models;
+
+DROP TABLE IF EXISTS models;
+
+DROP TABLE IF EXISTS platforms;
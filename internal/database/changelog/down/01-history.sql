@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS history;
+
+DROP SEQUENCE IF EXISTS seq_history;
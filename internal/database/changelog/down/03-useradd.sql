@@ -0,0 +1,2 @@
+ALTER TABLE
+    history DROP COLUMN user_id;
@@ -0,0 +1,19 @@
+-- The opaque context tokens can't be rebuilt from the messages, rolled back
+-- conversations start over with an empty context
+CREATE TABLE IF NOT EXISTS contexts (
+    user_id VARCHAR,
+    model_name VARCHAR REFERENCES models(name),
+    context INTEGER [],
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    PRIMARY KEY (user_id, model_name)
+);
+
+ALTER TABLE
+    threads
+ADD
+    COLUMN context INTEGER [] DEFAULT [];
+
+DROP TABLE IF EXISTS messages;
+
+DROP SEQUENCE IF EXISTS seq_messages;
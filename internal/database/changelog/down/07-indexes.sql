@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_transactions_platform;
+
+DROP INDEX IF EXISTS idx_transactions_user;
+
+DROP INDEX IF EXISTS idx_history_user;
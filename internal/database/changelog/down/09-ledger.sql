@@ -0,0 +1,33 @@
+CREATE TABLE user_balances_rollback AS
+SELECT
+    user_id,
+    platform_id,
+    balance
+FROM
+    user_balances;
+
+DROP VIEW user_balances;
+
+CREATE TABLE IF NOT EXISTS user_balances (
+    user_id VARCHAR,
+    platform_id VARCHAR REFERENCES platforms(id),
+    balance INTEGER DEFAULT 0,
+    PRIMARY KEY (user_id, platform_id)
+);
+
+INSERT INTO
+    user_balances
+SELECT
+    user_id,
+    platform_id,
+    balance
+FROM
+    user_balances_rollback;
+
+DROP TABLE user_balances_rollback;
+
+DROP TABLE IF EXISTS ledger_entries;
+
+DROP SEQUENCE IF EXISTS seq_ledger_transfers;
+
+DROP SEQUENCE IF EXISTS seq_ledger_entries;
@@ -0,0 +1,7 @@
+-- api_keys has indexes, the user keys are deleted and user_id is kept
+DROP INDEX IF EXISTS idx_api_keys_user;
+
+DELETE FROM
+    api_keys
+WHERE
+    user_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- models is referenced by other tables, vision is cleared instead of dropped
+UPDATE
+    models
+SET
+    vision = FALSE;
@@ -0,0 +1,11 @@
+-- models is referenced by other tables, the details are reset instead of dropped
+UPDATE
+    models
+SET
+    family = NULL,
+    parameter_size = NULL,
+    quantization = NULL,
+    context_length = NULL,
+    tools = FALSE,
+    embedding = FALSE,
+    thinking = FALSE;
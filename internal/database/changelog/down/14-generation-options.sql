@@ -0,0 +1,9 @@
+ALTER TABLE
+    threads DROP COLUMN options;
+
+-- models is referenced by other tables, the model options are cleared
+-- instead of dropped
+UPDATE
+    models
+SET
+    options = NULL;
@@ -0,0 +1,15 @@
+ALTER TABLE
+    threads DROP COLUMN tools;
+
+-- messages has an index, the tool messages are deleted and the tool columns
+-- of the rest cleared instead of dropped
+DELETE FROM
+    messages
+WHERE
+    role = 'tool';
+
+UPDATE
+    messages
+SET
+    tool_calls = NULL,
+    tool_name = NULL;
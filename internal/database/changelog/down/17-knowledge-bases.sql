@@ -0,0 +1,8 @@
+ALTER TABLE
+    threads DROP COLUMN knowledge_base;
+
+DROP TABLE IF EXISTS knowledge_chunks;
+
+DROP TABLE IF EXISTS knowledge_documents;
+
+DROP TABLE IF EXISTS knowledge_bases;
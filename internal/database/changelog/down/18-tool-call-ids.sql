@@ -0,0 +1,5 @@
+-- messages has an index, tool_call_id is cleared instead of dropped
+UPDATE
+    messages
+SET
+    tool_call_id = NULL;
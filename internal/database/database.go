@@ -1,15 +1,12 @@
 package database
 
 import (
-	"crypto/sha256"
 	"database/sql"
 	"embed"
-	"encoding/hex"
 	"fmt"
 	"log"
 	"log/slog"
-	"path/filepath"
-	"sort"
+	"slices"
 	"strings"
 	"time"
 
@@ -21,7 +18,7 @@ import (
 var (
 	duckdbClient *sql.DB
 
-	//go:embed changelog/*.sql
+	//go:embed changelog/*.sql changelog/down/*.sql
 	changeLogFiles embed.FS
 )
 
@@ -52,6 +49,45 @@ type Platform struct {
 	BuyingPower int    `json:"buying_power"`
 }
 
+// Model is a model added to the bot with the details Ollama reports for it
+type Model struct {
+	Name          string `json:"name"`
+	Family        string `json:"family"`
+	ParameterSize string `json:"parameter_size"`
+	Quantization  string `json:"quantization"`
+	ContextLength int    `json:"context_length"`
+	Vision        bool   `json:"vision"`
+	Tools         bool   `json:"tools"`
+	Embedding     bool   `json:"embedding"`
+	Thinking      bool   `json:"thinking"`
+}
+
+// Details summarises the model details, models added before the details
+// were recorded only have a name
+func (m Model) Details() string {
+	var details []string
+	for _, detail := range []string{m.Family, m.ParameterSize, m.Quantization} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if m.ContextLength > 0 {
+		details = append(details, fmt.Sprintf("%d context", m.ContextLength))
+	}
+
+	var capabilities []string
+	for capability, enabled := range map[string]bool{"vision": m.Vision, "tools": m.Tools, "embedding": m.Embedding, "thinking": m.Thinking} {
+		if enabled {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	if len(capabilities) > 0 {
+		slices.Sort(capabilities)
+		details = append(details, strings.Join(capabilities, ", "))
+	}
+	return strings.Join(details, " · ")
+}
+
 // PlatformModel represents the tokens of a model on a specific platform
 type PlatformModel struct {
 	PlatformID string `json:"platform_id"`
@@ -67,22 +103,38 @@ type History struct {
 	Prompt    string `json:"prompt"`
 }
 
-// UserContext track the user contexts
-type UserContext struct {
-	UserID    string    `json:"user_id"`
-	Name      string    `json:"name"`
-	ModelName string    `json:"model_name"`
-	Context   []int32   `json:"context"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-}
-
 // Thread records
 type Thread struct {
 	ThreadID  string
-	Context   []int32
 	Prompt    string
 	ModelName string
+	Options   map[string]any
+	Tools     bool
+	// KnowledgeBase grounds the replies of the thread, empty for none
+	KnowledgeBase string
+}
+
+// DMSettings are the per user defaults for direct message conversations
+type DMSettings struct {
+	UserID       string `json:"user_id"`
+	ModelName    string `json:"model_name"`
+	SystemPrompt string `json:"system_prompt"`
+}
+
+// Message is a single role tagged message of a conversation
+type Message struct {
+	ID             int       `json:"id"`
+	ConversationID string    `json:"conversation_id"`
+	Role           string    `json:"role"`
+	Content        string    `json:"content"`
+	ModelName      string    `json:"model_name"`
+	CreatedAt      time.Time `json:"created_at"`
+	// ToolCalls are the JSON encoded tool calls of an assistant message
+	ToolCalls string `json:"tool_calls,omitempty"`
+	// ToolName is the tool a tool message holds the result of
+	ToolName string `json:"tool_name,omitempty"`
+	// ToolCallID is the id of the tool call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
 }
 
 func init() {
@@ -110,96 +162,31 @@ func init() {
 		log.Fatalf("failed to create changelog table: %v", err)
 	}
 
-	if err := runMigrations(); err != nil {
-		log.Fatalf("migration failed: %v", err)
-	}
-
-	slog.Info("All migrations applied successfully.")
-}
-
-func runMigrations() error {
-	entries, err := changeLogFiles.ReadDir("changelog")
+	_, err = duckdbClient.Exec(`ALTER TABLE database_changelog ADD COLUMN IF NOT EXISTS rolled_back_at TIMESTAMP;`)
 	if err != nil {
-		return fmt.Errorf("failed to read embedded changelogs: %w", err)
+		log.Fatalf("failed to update changelog table: %v", err)
 	}
 
-	var files []string
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
-			files = append(files, entry.Name())
-		}
-	}
-
-	sort.Strings(files)
-
-	for i, file := range files {
-		id := i + 1
-
-		contents, err := changeLogFiles.ReadFile(filepath.Join("changelog", file))
-		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", file, err)
-		}
-
-		checksum := sha256.Sum256(contents)
-		checksumHex := hex.EncodeToString(checksum[:])
-
-		var appliedChecksum string
-		err = duckdbClient.QueryRow("SELECT checksum FROM database_changelog WHERE id = ?", id).Scan(&appliedChecksum)
-		if err == nil {
-			if appliedChecksum != checksumHex {
-				return fmt.Errorf("checksum mismatch for migration %s (id=%d). File has changed", file, id)
-			}
-			log.Printf("Skipping already applied migration %s", file)
-			continue
-		}
-
-		// Run changelogs in a transaction
-		tx, err := duckdbClient.Begin()
-		if err != nil {
-			return fmt.Errorf("failed to begin tx: %w", err)
-		}
-
-		_, err = tx.Exec(string(contents))
-		if err != nil {
-			_ = tx.Rollback()
-			_, _ = duckdbClient.Exec(`
-				INSERT INTO database_changelog (id, name, applied_at, checksum, success) VALUES (?, ?, ?, ?, false)
-			`, id, file, time.Now(), checksumHex)
-			return fmt.Errorf("failed to apply migration %s: %w", file, err)
-		}
-
-		err = tx.Commit()
-		if err != nil {
-			return fmt.Errorf("failed to commit migration %s: %w", file, err)
-		}
-
-		_, err = duckdbClient.Exec(`
-			INSERT INTO database_changelog (id, name, applied_at, checksum, success)
-			VALUES (?, ?, ?, ?, true)
-		`, id, file, time.Now(), checksumHex)
-		if err != nil {
-			return fmt.Errorf("failed to record migration %s: %w", file, err)
-		}
-
-		log.Printf("Applied migration %s", file)
+	if err = checkpoint(); err != nil {
+		log.Fatalf("failed to checkpoint changelog table: %v", err)
 	}
-
-	return nil
 }
 
-// AddTransaction inserts a new transaction into the database.
-func AddTransaction(tx Transaction) error {
-	_, err := duckdbClient.Exec(`
+// AddTransaction inserts a new transaction into the database and returns its ID.
+func AddTransaction(tx Transaction) (string, error) {
+	var id string
+	err := duckdbClient.QueryRow(`
         INSERT INTO transactions (user_id, platform_id, amount, date, status)
-        VALUES (?, ?, ?, ?, ?);
-    `, tx.UserID, tx.PlatformID, tx.Amount, tx.Date, tx.Status)
-	return err
+        VALUES (?, ?, ?, ?, ?)
+        RETURNING CAST(id AS VARCHAR);
+    `, tx.UserID, tx.PlatformID, tx.Amount, tx.Date, tx.Status).Scan(&id)
+	return id, err
 }
 
 // GetTransactionByID checks if a transaction exists and returns it by ID.
 func GetTransactionByID(id string) (*Transaction, error) {
 	row := duckdbClient.QueryRow(`
-        SELECT id, user_id, platform_id, amount, date, status
+        SELECT CAST(id AS VARCHAR), user_id, platform_id, amount, date, status
         FROM transactions
         WHERE id = ?;
     `, id)
@@ -215,7 +202,7 @@ func GetTransactionByID(id string) (*Transaction, error) {
 // GetTransactionsByPlatformID checks if a transaction exists and returns it by ID.
 func GetTransactionByPlatformID(id string) (transactions []*Transaction, err error) {
 	rows, err := duckdbClient.Query(`
-        SELECT id, user_id, platform_id, amount, date, status
+        SELECT CAST(id AS VARCHAR), user_id, platform_id, amount, date, status
         FROM transactions
         WHERE platform_id = ?;
     `, id)
@@ -258,7 +245,7 @@ func UpdateTransaction(tx Transaction) error {
 
 // ListPlatforms lists current supported platforms
 func ListPlatforms() (platforms []Platform, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM platforms;`)
+	rows, err := duckdbClient.Query(`SELECT id, name, buying_power FROM platforms;`)
 	if err != nil {
 		return nil, err
 	}
@@ -320,17 +307,24 @@ func AddPlatform(platform Platform) error {
 
 // RemovePlatform remove a platform by id
 func RemovePlatform(id string) error {
-	_, err := duckdbClient.Exec(`
-	DELETE FROM platform_modelss WHERE platform_id = ?;
-	DELETE FROM platforms WHERE id = ?;
-	`, id, id)
-	return err
+	// The platform models and api keys have to be committed before the
+	// platform is deleted, see RemoveModel
+	for _, query := range []string{
+		`DELETE FROM platform_models WHERE platform_id = ?;`,
+		`DELETE FROM api_keys WHERE platform_id = ?;`,
+		`DELETE FROM platforms WHERE id = ?;`,
+	} {
+		if _, err := duckdbClient.Exec(query, id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetPlatformModelCost returns the cost of a model for a specific platform
 func GetPlatformModelCost(platformID, modelName string) (int, error) {
 	row := duckdbClient.QueryRow(`
-        SELECT cost FROM platform_models
+        SELECT tokens FROM platform_models
         WHERE platform_id = ? AND model_name = ?;
     `, platformID, modelName)
 
@@ -342,7 +336,7 @@ func GetPlatformModelCost(platformID, modelName string) (int, error) {
 // GetPlatform returns the buying power for a platform
 func GetPlatform(platformID string) (Platform, error) {
 	row := duckdbClient.QueryRow(`
-        SELECT * FROM platforms
+        SELECT id, name, buying_power FROM platforms
         WHERE id = ?;
     `, platformID)
 
@@ -412,7 +406,7 @@ func SetPlatformModels(platformID, model string, tokens int) error {
 }
 
 // AddModel adds a model
-func AddModel(model string) error {
+func AddModel(model Model) error {
 	tx, err := duckdbClient.Begin()
 	if err != nil {
 		return err
@@ -420,9 +414,9 @@ func AddModel(model string) error {
 	defer tx.Rollback()
 
 	_, err = tx.Exec(`
-		INSERT INTO models (name)
-		VALUES (?);
-	`, model)
+		INSERT INTO models (name, family, parameter_size, quantization, context_length, vision, tools, embedding, thinking)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`, model.Name, model.Family, model.ParameterSize, model.Quantization, model.ContextLength, model.Vision, model.Tools, model.Embedding, model.Thinking)
 
 	if err != nil {
 		return err
@@ -440,7 +434,7 @@ func AddModel(model string) error {
 			return err
 		}
 		_, err = tx.Exec(`INSERT INTO platform_models (platform_id, model_name, tokens) VALUES (?, ?, 0);`,
-			platformID, model)
+			platformID, model.Name)
 		if err != nil {
 			return err
 		}
@@ -449,9 +443,54 @@ func AddModel(model string) error {
 	return tx.Commit()
 }
 
+// ListModelDetails lists the added models with their details
+func ListModelDetails() (models []Model, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT name, COALESCE(family, ''), COALESCE(parameter_size, ''), COALESCE(quantization, ''),
+			COALESCE(context_length, 0), vision, tools, embedding, thinking
+		FROM models
+		ORDER BY name;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var model Model
+		err = rows.Scan(&model.Name, &model.Family, &model.ParameterSize, &model.Quantization,
+			&model.ContextLength, &model.Vision, &model.Tools, &model.Embedding, &model.Thinking)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, rows.Err()
+}
+
+// ListChatModels lists the models with a platform price that can chat,
+// leaving out embedding models
+func ListChatModels() (models []Model, err error) {
+	prices, err := ListPlatformModels()
+	if err != nil {
+		return nil, err
+	}
+	details, err := ListModelDetails()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range details {
+		if _, ok := prices[model.Name]; ok && !model.Embedding {
+			models = append(models, model)
+		}
+	}
+	return
+}
+
 // ListModels lists current added models
 func ListModels() (models []string, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM models;`)
+	rows, err := duckdbClient.Query(`SELECT name FROM models;`)
 	if err != nil {
 		return nil, err
 	}
@@ -470,17 +509,24 @@ func ListModels() (models []string, err error) {
 
 // RemoveModel remove a model by id
 func RemoveModel(name string) error {
-	_, err := duckdbClient.Exec(`
-	DELETE FROM platform_models WHERE model_name = ?;
-	DELETE FROM models WHERE name = ?;
-	`, name, name)
-	return err
+	// DuckDB checks foreign keys against the state before the transaction,
+	// so the references have to be committed before the model is deleted
+	for _, query := range []string{
+		`DELETE FROM platform_models WHERE model_name = ?;`,
+		`UPDATE dm_settings SET model_name = NULL WHERE model_name = ?;`,
+		`DELETE FROM models WHERE name = ?;`,
+	} {
+		if _, err := duckdbClient.Exec(query, name); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetModel returns the model
 func GetModel(name string) (string, error) {
 	row := duckdbClient.QueryRow(`
-        SELECT * FROM models
+        SELECT name FROM models
         WHERE name = ?;
     `, name)
 
@@ -488,6 +534,32 @@ func GetModel(name string) (string, error) {
 	return name, row.Scan(&mn)
 }
 
+// SetModelVision sets if the model accepts images
+func SetModelVision(name string, vision bool) error {
+	result, err := duckdbClient.Exec(`UPDATE models SET vision = ? WHERE name = ?;`, vision, name)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ModelSupportsVision returns if the model accepts images
+func ModelSupportsVision(name string) (vision bool, err error) {
+	err = duckdbClient.QueryRow(`SELECT vision FROM models WHERE name = ?;`, name).Scan(&vision)
+	return
+}
+
+// ModelSupportsTools returns if the model can call tools
+func ModelSupportsTools(name string) (tools bool, err error) {
+	err = duckdbClient.QueryRow(`SELECT COALESCE(tools, FALSE) FROM models WHERE name = ?;`, name).Scan(&tools)
+	return
+}
+
 func AddHistory(hist History) error {
 	tx, err := duckdbClient.Begin()
 	if err != nil {
@@ -507,7 +579,7 @@ func AddHistory(hist History) error {
 }
 
 func ListHistory(index int) (history []History, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM history WHERE id > ? ORDER BY id ASC LIMIT 5;`, index)
+	rows, err := duckdbClient.Query(`SELECT id, model_name, prompt, user_id FROM history WHERE id > ? ORDER BY id ASC LIMIT 5;`, index)
 
 	if err != nil {
 		return nil, err
@@ -528,11 +600,11 @@ func ListHistory(index int) (history []History, err error) {
 }
 
 func CountHistory() int {
-	row := duckdbClient.QueryRow(`SELECT currval('seq_history') AS currval;`)
+	row := duckdbClient.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM history;`)
 	var index int32
 	err := row.Scan(&index)
 	if err != nil {
-		slog.Error("Error fetching current sequence value:", slog.Any("err", err))
+		slog.Error("Error fetching history count:", slog.Any("err", err))
 	}
 	return int(index)
 }
@@ -549,48 +621,13 @@ func GetHistory(id int) (history History, err error) {
 	return History{ID: id, ModelName: model_name, Prompt: prompt}, err
 }
 
-func GetContext(userID, modelName string) []int32 {
-	row := duckdbClient.QueryRow(`
-		SELECT context FROM contexts
-		WHERE user_id = ? AND model_name = ?;
-	`, userID, modelName)
-
-	var raw []interface{}
-	err := row.Scan(&raw)
-	if err != nil && err != sql.ErrNoRows {
-		slog.Error("Error fetching contexts:", slog.Any("err", err))
-	}
-	context := make([]int32, len(raw))
-	for i, v := range raw {
-		context[i] = v.(int32)
-	}
-
-	return context
-}
-
-func SetContext(userContext UserContext) error {
-	tx, err := duckdbClient.Begin()
+// AddThread inserts a new thread record, seeding its conversation with the system prompt.
+func AddThread(modelName, systemPrompt, thread_id string, options map[string]any, knowledgeBase string) error {
+	encoded, err := encodeOptions(options)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	now := time.Now()
-	_, err = tx.Exec(`
-		INSERT INTO contexts (user_id, model_name, context, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?) 
-		ON CONFLICT DO UPDATE SET 
-		context = EXCLUDED.context,
-		updated_at = EXCLUDED.updated_at;
-	`, userContext.UserID, userContext.ModelName, userContext.Context, now, now)
-	if err != nil {
-		return err
-	}
-	return tx.Commit()
-}
-
-// AddThread inserts a new thread record with an empty context slice.
-func AddThread(modelName, systemPrompt, thread_id string) error {
 	tx, err := duckdbClient.Begin()
 	if err != nil {
 		return err
@@ -598,60 +635,154 @@ func AddThread(modelName, systemPrompt, thread_id string) error {
 	defer tx.Rollback()
 
 	_, err = tx.Exec(`
-		INSERT INTO threads (thread_id, model_name, system_prompt, context)
-		VALUES (?, ?, ?, ?);
-	`, thread_id, modelName, systemPrompt, []int{})
+		INSERT INTO threads (thread_id, model_name, system_prompt, options, knowledge_base)
+		VALUES (?, ?, ?, ?, ?);
+	`, thread_id, modelName, systemPrompt, encoded, nullString(knowledgeBase))
 
 	if err != nil {
 		return err
 	}
+
+	if systemPrompt != "" {
+		_, err = tx.Exec(`
+			INSERT INTO messages (conversation_id, role, content, model_name)
+			VALUES (?, 'system', ?, ?);
+		`, thread_id, systemPrompt, modelName)
+		if err != nil {
+			return err
+		}
+	}
 	return tx.Commit()
 }
 
 func GetThread(id string) (Thread, error) {
 	row := duckdbClient.QueryRow(`
-		SELECT * FROM threads
+		SELECT thread_id, model_name, system_prompt, options, COALESCE(tools, TRUE), COALESCE(knowledge_base, '') FROM threads
 		WHERE thread_id = ?;
 	`, id)
 
-	var thread_id, model_name, system_prompt string
-	var context []interface{}
-	err := row.Scan(&thread_id, &model_name, &system_prompt, &context)
+	var thread_id, model_name, system_prompt, knowledge_base string
+	var options sql.NullString
+	var tools bool
+	err := row.Scan(&thread_id, &model_name, &system_prompt, &options, &tools, &knowledge_base)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return Thread{}, err
 		} else {
-			slog.Error("Error fetching contexts:", slog.Any("err", err))
+			slog.Error("Error fetching thread:", slog.Any("err", err))
 		}
 	}
 
-	contextSlice := make([]int32, len(context))
-	for i, v := range context {
-		contextSlice[i] = v.(int32)
+	thread := Thread{
+		ThreadID:      thread_id,
+		Prompt:        system_prompt,
+		ModelName:     model_name,
+		Tools:         tools,
+		KnowledgeBase: knowledge_base,
+	}
+	thread.Options, err = decodeOptions(options)
+	if err != nil {
+		slog.Error("Error decoding thread options:", slog.Any("err", err))
 	}
+	return thread, nil
+}
 
-	return Thread{
-		Context:   contextSlice,
-		ThreadID:  thread_id,
-		Prompt:    system_prompt,
-		ModelName: model_name,
-	}, nil
+// SetThreadTools sets if the model of a thread may call tools
+func SetThreadTools(threadID string, enabled bool) error {
+	_, err := duckdbClient.Exec(`UPDATE threads SET tools = ? WHERE thread_id = ?;`, enabled, threadID)
+	return err
 }
 
-func UpdateThreadContext(id string, context []int32) error {
+// AddMessages appends messages to their conversations
+func AddMessages(messages ...Message) error {
 	tx, err := duckdbClient.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
-		UPDATE threads
-		SET context = ?
-		WHERE thread_id = ?;
-		`, context, id)
-	if err != nil {
-		return err
+	for _, message := range messages {
+		_, err = tx.Exec(`
+			INSERT INTO messages (conversation_id, role, content, model_name, created_at, tool_calls, tool_name, tool_call_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+		`, message.ConversationID, message.Role, message.Content, message.ModelName, time.Now(), nullString(message.ToolCalls), nullString(message.ToolName), nullString(message.ToolCallID))
+		if err != nil {
+			return err
+		}
 	}
 	return tx.Commit()
 }
+
+// GetMessages returns the messages of a conversation in the order they were added
+func GetMessages(conversationID string) (messages []Message, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT id, conversation_id, role, content, model_name, created_at, tool_calls, tool_name, tool_call_id
+		FROM messages
+		WHERE conversation_id = ?
+		ORDER BY id ASC;
+	`, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message Message
+		var content, model_name, tool_calls, tool_name, tool_call_id sql.NullString
+
+		err = rows.Scan(&message.ID, &message.ConversationID, &message.Role, &content, &model_name, &message.CreatedAt, &tool_calls, &tool_name, &tool_call_id)
+		if err != nil {
+			return nil, err
+		}
+		message.Content = content.String
+		message.ModelName = model_name.String
+		message.ToolCalls = tool_calls.String
+		message.ToolName = tool_name.String
+		message.ToolCallID = tool_call_id.String
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// ClearMessages removes all messages of a conversation
+func ClearMessages(conversationID string) error {
+	_, err := duckdbClient.Exec(`DELETE FROM messages WHERE conversation_id = ?;`, conversationID)
+	return err
+}
+
+// GetDMSettings returns the direct message settings of a user
+func GetDMSettings(userID string) (DMSettings, error) {
+	row := duckdbClient.QueryRow(`
+		SELECT user_id, model_name, system_prompt FROM dm_settings
+		WHERE user_id = ?;
+	`, userID)
+
+	var model_name, system_prompt sql.NullString
+	settings := DMSettings{UserID: userID}
+	err := row.Scan(&settings.UserID, &model_name, &system_prompt)
+	settings.ModelName = model_name.String
+	settings.SystemPrompt = system_prompt.String
+	return settings, err
+}
+
+// SetDMSettings stores the direct message settings of a user
+func SetDMSettings(settings DMSettings) error {
+	var model_name sql.NullString
+	if settings.ModelName != "" {
+		model_name = sql.NullString{String: settings.ModelName, Valid: true}
+	}
+
+	_, err := duckdbClient.Exec(`
+		INSERT INTO dm_settings (user_id, model_name, system_prompt)
+		VALUES (?, ?, ?)
+		ON CONFLICT DO UPDATE SET
+		model_name = EXCLUDED.model_name,
+		system_prompt = EXCLUDED.system_prompt;
+	`, settings.UserID, model_name, settings.SystemPrompt)
+	return err
+}
+
+// nullString stores empty strings as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
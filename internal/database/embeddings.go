@@ -0,0 +1,287 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ChannelChunk is a run of channel messages embedded for retrieval, it is
+// linked to by its first message
+type ChannelChunk struct {
+	MessageID string    `json:"message_id"`
+	GuildID   string    `json:"guild_id"`
+	ChannelID string    `json:"channel_id"`
+	Content   string    `json:"content"`
+	ModelName string    `json:"model_name"`
+	Embedding []float32 `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	// Similarity is the cosine similarity to the searched embedding
+	Similarity float32 `json:"similarity"`
+}
+
+// ReplaceChannelChunks replaces the indexed chunks of a channel
+func ReplaceChannelChunks(channelID string, chunks []ChannelChunk) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM channel_chunks WHERE channel_id = ?;`, channelID); err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		_, err := tx.Exec(`
+			INSERT INTO channel_chunks (message_id, guild_id, channel_id, content, model_name, embedding, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?);
+		`, chunk.MessageID, chunk.GuildID, channelID, chunk.Content, chunk.ModelName, chunk.Embedding, chunk.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// CountChannelChunks returns how many chunks of a channel are indexed
+func CountChannelChunks(channelID string) (count int, err error) {
+	err = duckdbClient.QueryRow(`SELECT COUNT(*) FROM channel_chunks WHERE channel_id = ?;`, channelID).Scan(&count)
+	return
+}
+
+// SearchChannelChunks returns the chunks of a channel most similar to the
+// embedding. Only chunks embedded by the same model are compared, their
+// vectors are cast to an array of the embedding size for the similarity.
+func SearchChannelChunks(channelID, modelName string, embedding []float32, limit int) (chunks []ChannelChunk, err error) {
+	rows, err := duckdbClient.Query(fmt.Sprintf(`
+		SELECT message_id, guild_id, channel_id, content, model_name, created_at,
+		array_cosine_similarity(embedding::FLOAT[%[1]d], ?::FLOAT[%[1]d]) AS similarity
+		FROM channel_chunks
+		WHERE channel_id = ? AND model_name = ? AND len(embedding) = %[1]d
+		ORDER BY similarity DESC
+		LIMIT ?;
+	`, len(embedding)), embedding, channelID, modelName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunk ChannelChunk
+		err := rows.Scan(&chunk.MessageID, &chunk.GuildID, &chunk.ChannelID, &chunk.Content, &chunk.ModelName, &chunk.CreatedAt, &chunk.Similarity)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+// KnowledgeBase is a named set of documents threads can be grounded in
+type KnowledgeBase struct {
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Documents int       `json:"documents"`
+	Chunks    int       `json:"chunks"`
+}
+
+// KnowledgeDocument is an uploaded document of a knowledge base, the text is
+// kept to embed it again on a reindex
+type KnowledgeDocument struct {
+	KnowledgeBase string `json:"knowledge_base"`
+	FileName      string `json:"file_name"`
+	Content       string `json:"content"`
+	AddedBy       string `json:"added_by"`
+}
+
+// KnowledgeChunk is an embedded piece of a knowledge base document
+type KnowledgeChunk struct {
+	KnowledgeBase string    `json:"knowledge_base"`
+	FileName      string    `json:"file_name"`
+	Content       string    `json:"content"`
+	ModelName     string    `json:"model_name"`
+	Embedding     []float32 `json:"-"`
+	// Similarity is the cosine similarity to the searched embedding
+	Similarity float32 `json:"similarity"`
+}
+
+// AddKnowledgeDocument adds a document with its chunks to a knowledge base,
+// creating the knowledge base when it doesn't exist yet. A document with the
+// same file name is replaced.
+func AddKnowledgeDocument(document KnowledgeDocument, chunks []KnowledgeChunk) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO knowledge_bases (name, created_by) VALUES (?, ?)
+		ON CONFLICT DO NOTHING;
+	`, document.KnowledgeBase, document.AddedBy)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO knowledge_documents (knowledge_base, file_name, content, added_by)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO UPDATE SET
+		content = EXCLUDED.content,
+		added_by = EXCLUDED.added_by;
+	`, document.KnowledgeBase, document.FileName, document.Content, document.AddedBy)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM knowledge_chunks WHERE knowledge_base = ? AND file_name = ?;`, document.KnowledgeBase, document.FileName)
+	if err != nil {
+		return err
+	}
+	if err := insertKnowledgeChunks(tx, chunks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListKnowledgeBases lists the knowledge bases with their document and chunk counts
+func ListKnowledgeBases() (knowledgeBases []KnowledgeBase, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT kb.name, COALESCE(kb.created_by, ''), kb.created_at,
+		(SELECT COUNT(*) FROM knowledge_documents d WHERE d.knowledge_base = kb.name),
+		(SELECT COUNT(*) FROM knowledge_chunks c WHERE c.knowledge_base = kb.name)
+		FROM knowledge_bases kb
+		ORDER BY kb.name;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var knowledgeBase KnowledgeBase
+		err := rows.Scan(&knowledgeBase.Name, &knowledgeBase.CreatedBy, &knowledgeBase.CreatedAt, &knowledgeBase.Documents, &knowledgeBase.Chunks)
+		if err != nil {
+			return nil, err
+		}
+		knowledgeBases = append(knowledgeBases, knowledgeBase)
+	}
+	return knowledgeBases, rows.Err()
+}
+
+// KnowledgeBaseExists returns if a knowledge base with the name exists
+func KnowledgeBaseExists(name string) (exists bool, err error) {
+	err = duckdbClient.QueryRow(`SELECT COUNT(*) > 0 FROM knowledge_bases WHERE name = ?;`, name).Scan(&exists)
+	return
+}
+
+// GetKnowledgeDocuments returns the documents of a knowledge base
+func GetKnowledgeDocuments(name string) (documents []KnowledgeDocument, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT knowledge_base, file_name, content, COALESCE(added_by, '') FROM knowledge_documents
+		WHERE knowledge_base = ?
+		ORDER BY file_name;
+	`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var document KnowledgeDocument
+		if err := rows.Scan(&document.KnowledgeBase, &document.FileName, &document.Content, &document.AddedBy); err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, rows.Err()
+}
+
+// ReplaceKnowledgeChunks replaces all chunks of a knowledge base
+func ReplaceKnowledgeChunks(name string, chunks []KnowledgeChunk) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM knowledge_chunks WHERE knowledge_base = ?;`, name); err != nil {
+		return err
+	}
+	if err := insertKnowledgeChunks(tx, chunks); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveKnowledgeBase removes a knowledge base with its documents, the
+// threads grounded in it continue without. It returns sql.ErrNoRows when
+// there is no knowledge base with the name.
+func RemoveKnowledgeBase(name string) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM knowledge_chunks WHERE knowledge_base = ?;`,
+		`DELETE FROM knowledge_documents WHERE knowledge_base = ?;`,
+		`UPDATE threads SET knowledge_base = NULL WHERE knowledge_base = ?;`,
+	} {
+		if _, err := tx.Exec(query, name); err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`DELETE FROM knowledge_bases WHERE name = ?;`, name)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return tx.Commit()
+}
+
+// SearchKnowledgeChunks returns the chunks of a knowledge base most similar
+// to the embedding, comparing only chunks embedded by the same model
+func SearchKnowledgeChunks(name, modelName string, embedding []float32, limit int) (chunks []KnowledgeChunk, err error) {
+	rows, err := duckdbClient.Query(fmt.Sprintf(`
+		SELECT knowledge_base, file_name, content, model_name,
+		array_cosine_similarity(embedding::FLOAT[%[1]d], ?::FLOAT[%[1]d]) AS similarity
+		FROM knowledge_chunks
+		WHERE knowledge_base = ? AND model_name = ? AND len(embedding) = %[1]d
+		ORDER BY similarity DESC
+		LIMIT ?;
+	`, len(embedding)), embedding, name, modelName, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunk KnowledgeChunk
+		if err := rows.Scan(&chunk.KnowledgeBase, &chunk.FileName, &chunk.Content, &chunk.ModelName, &chunk.Similarity); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, rows.Err()
+}
+
+func insertKnowledgeChunks(tx *sql.Tx, chunks []KnowledgeChunk) error {
+	for _, chunk := range chunks {
+		_, err := tx.Exec(`
+			INSERT INTO knowledge_chunks (knowledge_base, file_name, content, model_name, embedding)
+			VALUES (?, ?, ?, ?, ?);
+		`, chunk.KnowledgeBase, chunk.FileName, chunk.Content, chunk.ModelName, chunk.Embedding)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
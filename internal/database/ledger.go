@@ -0,0 +1,152 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Accounts on the other side of the user accounts in the ledger
+const (
+	AccountUsage = "usage"
+	AccountAdmin = "admin"
+)
+
+// Transaction statuses
+const (
+	TransactionPending  = "pending"
+	TransactionApproved = "approved"
+	TransactionRejected = "rejected"
+)
+
+// ErrTransactionFinal is returned when changing the status of an approved transaction
+var ErrTransactionFinal = errors.New("approved transactions can't be changed")
+
+// LedgerEntry is one side of a transfer, every transfer sums up to zero
+type LedgerEntry struct {
+	ID         int       `json:"id"`
+	TransferID int       `json:"transfer_id"`
+	Account    string    `json:"account"`
+	PlatformID string    `json:"platform_id"`
+	Amount     int       `json:"amount"`
+	Reference  string    `json:"reference"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// UserAccount returns the ledger account of a user
+func UserAccount(userID string) string {
+	return "user:" + userID
+}
+
+// PlatformAccount returns the ledger account coins are bought from on a platform
+func PlatformAccount(platformID string) string {
+	return "platform:" + platformID
+}
+
+// Transfer moves coins on a platform between two ledger accounts
+func Transfer(from, to, platformID string, amount int, reference string) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := transfer(tx, from, to, platformID, amount, reference); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func transfer(tx *sql.Tx, from, to, platformID string, amount int, reference string) error {
+	var transferID int
+	err := tx.QueryRow(`SELECT NEXTVAL('seq_ledger_transfers');`).Scan(&transferID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, entry := range []LedgerEntry{
+		{Account: from, Amount: -amount},
+		{Account: to, Amount: amount},
+	} {
+		_, err = tx.Exec(`
+			INSERT INTO ledger_entries (transfer_id, account, platform_id, amount, reference, created_at)
+			VALUES (?, ?, ?, ?, ?, ?);
+		`, transferID, entry.Account, platformID, entry.Amount, reference, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListLedgerEntries returns the most recent entries of an account
+func ListLedgerEntries(account string, limit int) (entries []LedgerEntry, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT id, transfer_id, account, platform_id, amount, reference, created_at
+		FROM ledger_entries
+		WHERE account = ?
+		ORDER BY id DESC
+		LIMIT ?;
+	`, account, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry LedgerEntry
+		var reference sql.NullString
+		err = rows.Scan(&entry.ID, &entry.TransferID, &entry.Account, &entry.PlatformID, &entry.Amount, &reference, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.Reference = reference.String
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateTransactionStatus changes the status of a transaction. Approving a
+// transaction credits the user with the amount times the buying power of the
+// platform, approved transactions are final.
+func UpdateTransactionStatus(id, status string) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID, platformID, current string
+	var amount, buyingPower int
+	err = tx.QueryRow(`
+		SELECT t.user_id, t.platform_id, t.amount, t.status, p.buying_power
+		FROM transactions AS t
+		JOIN platforms AS p
+		ON t.platform_id = p.id
+		WHERE t.id = ?;
+	`, id).Scan(&userID, &platformID, &amount, &current, &buyingPower)
+	if err != nil {
+		return err
+	}
+
+	if current == status {
+		return nil
+	}
+	if current == TransactionApproved {
+		return ErrTransactionFinal
+	}
+
+	_, err = tx.Exec(`UPDATE transactions SET status = ? WHERE id = ?;`, status, id)
+	if err != nil {
+		return err
+	}
+
+	if status == TransactionApproved {
+		err = transfer(tx, PlatformAccount(platformID), UserAccount(userID), platformID, amount*buyingPower, "transaction:"+id)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,265 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Migration is a changelog file together with its state in database_changelog
+type Migration struct {
+	ID       int
+	Name     string
+	Checksum string
+	HasDown  bool
+
+	Recorded        bool
+	Success         bool
+	AppliedAt       time.Time
+	RolledBackAt    *time.Time
+	AppliedChecksum string
+}
+
+// Applied reports if the migration is currently in effect
+func (m Migration) Applied() bool {
+	return m.Recorded && m.Success && m.RolledBackAt == nil
+}
+
+// Status returns a short human readable state of the migration
+func (m Migration) Status() string {
+	switch {
+	case !m.Recorded:
+		return "pending"
+	case !m.Success:
+		return "failed"
+	case m.RolledBackAt != nil:
+		return "rolled back"
+	case m.AppliedChecksum != m.Checksum:
+		return "checksum mismatch"
+	default:
+		return "applied"
+	}
+}
+
+// Migrate applies all pending migrations and verifies the resulting schema
+func Migrate() error {
+	if err := MigrateUp(); err != nil {
+		return err
+	}
+	slog.Info("All migrations applied successfully.")
+
+	return verifySchema()
+}
+
+// ListMigrations returns every changelog file with its recorded state
+func ListMigrations() ([]Migration, error) {
+	entries, err := changeLogFiles.ReadDir("changelog")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded changelogs: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			files = append(files, entry.Name())
+		}
+	}
+
+	sort.Strings(files)
+
+	var migrations []Migration
+	for i, file := range files {
+		contents, err := changeLogFiles.ReadFile(filepath.Join("changelog", file))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		checksum := sha256.Sum256(contents)
+
+		migration := Migration{
+			ID:       i + 1,
+			Name:     file,
+			Checksum: hex.EncodeToString(checksum[:]),
+		}
+		_, err = fs.Stat(changeLogFiles, filepath.Join("changelog", "down", file))
+		migration.HasDown = err == nil
+
+		var rolledBackAt sql.NullTime
+		err = duckdbClient.QueryRow(`
+			SELECT checksum, success, applied_at, rolled_back_at
+			FROM database_changelog
+			WHERE id = ?;
+		`, migration.ID).Scan(&migration.AppliedChecksum, &migration.Success, &migration.AppliedAt, &rolledBackAt)
+		switch err {
+		case nil:
+			migration.Recorded = true
+			if rolledBackAt.Valid {
+				migration.RolledBackAt = &rolledBackAt.Time
+			}
+		case sql.ErrNoRows:
+		default:
+			return nil, fmt.Errorf("failed to read changelog of %s: %w", file, err)
+		}
+
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// MigrateUp applies every migration that is pending, failed or rolled back
+func MigrateUp() error {
+	migrations, err := ListMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.Applied() {
+			if migration.AppliedChecksum != migration.Checksum {
+				return fmt.Errorf("checksum mismatch for migration %s (id=%d). File has changed, run `migrate repair` to accept it", migration.Name, migration.ID)
+			}
+			log.Printf("Skipping already applied migration %s", migration.Name)
+			continue
+		}
+
+		contents, err := changeLogFiles.ReadFile(filepath.Join("changelog", migration.Name))
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", migration.Name, err)
+		}
+
+		if err := execMigration(string(contents)); err != nil {
+			_ = recordMigration(migration, false)
+			return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+		}
+
+		if err := recordMigration(migration, true); err != nil {
+			return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+		}
+
+		log.Printf("Applied migration %s", migration.Name)
+	}
+
+	return checkpoint()
+}
+
+// MigrateDown rolls back the last steps applied migrations using their down
+// files. DuckDB can't drop a column from a table that other tables reference
+// or that has an index, the down files of such columns clear them instead and
+// the up migrations only add columns when they are missing.
+func MigrateDown(steps int) error {
+	migrations, err := ListMigrations()
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := migrations[i]
+		if !migration.Applied() {
+			continue
+		}
+		if !migration.HasDown {
+			return fmt.Errorf("migration %s has no down migration", migration.Name)
+		}
+
+		contents, err := changeLogFiles.ReadFile(filepath.Join("changelog", "down", migration.Name))
+		if err != nil {
+			return fmt.Errorf("failed to read down migration %s: %w", migration.Name, err)
+		}
+
+		if err := execMigration(string(contents)); err != nil {
+			return fmt.Errorf("failed to roll back migration %s: %w", migration.Name, err)
+		}
+
+		_, err = duckdbClient.Exec(`
+			UPDATE database_changelog
+			SET rolled_back_at = ?
+			WHERE id = ?;
+		`, time.Now(), migration.ID)
+		if err != nil {
+			return fmt.Errorf("failed to record rollback of %s: %w", migration.Name, err)
+		}
+
+		log.Printf("Rolled back migration %s", migration.Name)
+		steps--
+	}
+
+	return checkpoint()
+}
+
+// RepairMigrations clears failed changelog records so they are retried and
+// accepts the current checksum of changed files. It returns what was repaired.
+func RepairMigrations() (repaired []string, err error) {
+	migrations, err := ListMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range migrations {
+		switch {
+		case migration.Recorded && !migration.Success:
+			_, err = duckdbClient.Exec(`DELETE FROM database_changelog WHERE id = ?;`, migration.ID)
+			if err != nil {
+				return repaired, err
+			}
+			repaired = append(repaired, fmt.Sprintf("cleared failed record of %s", migration.Name))
+		case migration.Recorded && migration.AppliedChecksum != migration.Checksum:
+			_, err = duckdbClient.Exec(`UPDATE database_changelog SET checksum = ? WHERE id = ?;`, migration.Checksum, migration.ID)
+			if err != nil {
+				return repaired, err
+			}
+			repaired = append(repaired, fmt.Sprintf("accepted new checksum of %s", migration.Name))
+		}
+	}
+
+	result, err := duckdbClient.Exec(`DELETE FROM database_changelog WHERE id > ?;`, len(migrations))
+	if err != nil {
+		return repaired, err
+	}
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		repaired = append(repaired, fmt.Sprintf("removed %d records without a changelog file", removed))
+	}
+	return repaired, nil
+}
+
+// execMigration runs the changelog contents in a transaction
+func execMigration(contents string) error {
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(contents); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkpoint writes the WAL to the database file. DuckDB fails to replay an
+// ALTER TABLE on a table with a function default, like NEXTVAL or
+// CURRENT_TIMESTAMP, from the WAL on the next start.
+func checkpoint() error {
+	_, err := duckdbClient.Exec(`CHECKPOINT;`)
+	return err
+}
+
+func recordMigration(migration Migration, success bool) error {
+	_, err := duckdbClient.Exec(`
+		INSERT INTO database_changelog (id, name, applied_at, checksum, success, rolled_back_at)
+		VALUES (?, ?, ?, ?, ?, NULL)
+		ON CONFLICT (id) DO UPDATE SET
+		name = EXCLUDED.name,
+		applied_at = EXCLUDED.applied_at,
+		checksum = EXCLUDED.checksum,
+		success = EXCLUDED.success,
+		rolled_back_at = NULL;
+	`, migration.ID, migration.Name, time.Now(), migration.Checksum, success)
+	return err
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// SetModelOptions sets the default generation options of a model, nil clears them
+func SetModelOptions(name string, options map[string]any) error {
+	encoded, err := encodeOptions(options)
+	if err != nil {
+		return err
+	}
+
+	result, err := duckdbClient.Exec(`UPDATE models SET options = ? WHERE name = ?;`, encoded, name)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetModelOptions returns the default generation options of a model
+func GetModelOptions(name string) (map[string]any, error) {
+	var encoded sql.NullString
+	err := duckdbClient.QueryRow(`SELECT options FROM models WHERE name = ?;`, name).Scan(&encoded)
+	if err != nil {
+		return nil, err
+	}
+	return decodeOptions(encoded)
+}
+
+// SetThreadOptions sets the generation options of a thread, nil clears them
+func SetThreadOptions(threadID string, options map[string]any) error {
+	encoded, err := encodeOptions(options)
+	if err != nil {
+		return err
+	}
+
+	_, err = duckdbClient.Exec(`UPDATE threads SET options = ? WHERE thread_id = ?;`, encoded, threadID)
+	return err
+}
+
+// The options are stored as the json object Ollama accepts
+func encodeOptions(options map[string]any) (sql.NullString, error) {
+	if len(options) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func decodeOptions(encoded sql.NullString) (options map[string]any, err error) {
+	if !encoded.Valid || encoded.String == "" {
+		return nil, nil
+	}
+	err = json.Unmarshal([]byte(encoded.String), &options)
+	return
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// ErrInsufficientBalance is returned when a user holds no coins on any platform pricing the model
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// chargeMu serializes the balance checks and debits of ChargeUsage
+var chargeMu sync.Mutex
+
+// Balance is the coins a user holds on a platform, summed up from the ledger
+type Balance struct {
+	UserID     string `json:"user_id"`
+	PlatformID string `json:"platform_id"`
+	Balance    int    `json:"balance"`
+}
+
+// Charge is the cost of a generation debited from a user
+type Charge struct {
+	PlatformID string `json:"platform_id"`
+	Tokens     int    `json:"tokens"`
+	Coins      int    `json:"coins"`
+}
+
+// CheckQuota returns ErrInsufficientBalance when the model is priced and the
+// user has no coins left on any of the platforms pricing it. Models without
+// a price on any platform are free to use.
+func CheckQuota(userID, modelName string) error {
+	row := duckdbClient.QueryRow(`
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE ub.balance > 0)
+		FROM platform_models AS pm
+		LEFT JOIN user_balances AS ub
+		ON ub.platform_id = pm.platform_id AND ub.user_id = ?
+		WHERE pm.model_name = ? AND pm.tokens > 0;
+	`, userID, modelName)
+
+	var priced, funded int
+	if err := row.Scan(&priced, &funded); err != nil {
+		return err
+	}
+	if priced > 0 && funded == 0 {
+		return ErrInsufficientBalance
+	}
+	return nil
+}
+
+// ChargeUsage debits the cost of the used tokens from the cheapest platform
+// able to pay for it, falling back to the platform with the largest balance.
+// The charge is clamped to that balance so it never goes below zero, which
+// makes CheckQuota refuse the next request. The returned charge is empty
+// when the model isn't priced.
+func ChargeUsage(userID, modelName string, tokens int) (Charge, error) {
+	// Charges insert into the ledger without conflicting in DuckDB, so
+	// concurrent charges are serialized to not both spend the same balance
+	chargeMu.Lock()
+	defer chargeMu.Unlock()
+
+	tx, err := duckdbClient.Begin()
+	if err != nil {
+		return Charge{}, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(`
+		SELECT pm.platform_id, LEAST(CAST(CEIL(? / pm.tokens) AS INTEGER), ub.balance) AS coins
+		FROM platform_models AS pm
+		JOIN user_balances AS ub
+		ON ub.platform_id = pm.platform_id AND ub.user_id = ?
+		WHERE pm.model_name = ? AND pm.tokens > 0 AND ub.balance > 0
+		ORDER BY ub.balance >= CEIL(? / pm.tokens) DESC, coins ASC, ub.balance DESC
+		LIMIT 1;
+	`, float64(tokens), userID, modelName, float64(tokens))
+
+	charge := Charge{Tokens: tokens}
+	err = row.Scan(&charge.PlatformID, &charge.Coins)
+	if err == sql.ErrNoRows {
+		if quotaErr := CheckQuota(userID, modelName); quotaErr != nil {
+			return charge, quotaErr
+		}
+		return charge, nil
+	}
+	if err != nil {
+		return charge, err
+	}
+
+	if err := transfer(tx, UserAccount(userID), AccountUsage, charge.PlatformID, charge.Coins, "model:"+modelName); err != nil {
+		return charge, err
+	}
+	return charge, tx.Commit()
+}
+
+// ListBalances returns the balances of a user
+func ListBalances(userID string) (balances []Balance, err error) {
+	rows, err := duckdbClient.Query(`
+		SELECT user_id, platform_id, balance FROM user_balances
+		WHERE user_id = ?
+		ORDER BY platform_id;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var balance Balance
+		if err := rows.Scan(&balance.UserID, &balance.PlatformID, &balance.Balance); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+// AddBalance grants coins to a user on a platform
+func AddBalance(userID, platformID string, coins int) error {
+	return Transfer(AccountAdmin, UserAccount(userID), platformID, coins, "admin grant")
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// expectedSchema lists the tables and columns the queries in this package rely on
+var expectedSchema = map[string][]string{
+	"platforms":           {"id", "name", "buying_power"},
+	"models":              {"name", "vision", "family", "parameter_size", "quantization", "context_length", "tools", "embedding", "thinking", "options"},
+	"platform_models":     {"platform_id", "model_name", "tokens"},
+	"transactions":        {"id", "user_id", "platform_id", "model_name", "amount", "date", "status"},
+	"history":             {"id", "model_name", "prompt", "user_id"},
+	"threads":             {"thread_id", "model_name", "system_prompt", "options", "tools", "knowledge_base"},
+	"messages":            {"id", "conversation_id", "role", "content", "model_name", "created_at", "tool_calls", "tool_name", "tool_call_id"},
+	"dm_settings":         {"user_id", "model_name", "system_prompt"},
+	"user_balances":       {"user_id", "platform_id", "balance"},
+	"ledger_entries":      {"id", "transfer_id", "account", "platform_id", "amount", "reference", "created_at"},
+	"api_keys":            {"id", "platform_id", "user_id", "name", "key_hash", "created_at", "last_used_at", "revoked_at"},
+	"channel_chunks":      {"message_id", "guild_id", "channel_id", "content", "model_name", "embedding", "created_at"},
+	"knowledge_bases":     {"name", "created_by", "created_at"},
+	"knowledge_documents": {"knowledge_base", "file_name", "content", "added_by", "created_at"},
+	"knowledge_chunks":    {"knowledge_base", "file_name", "content", "model_name", "embedding"},
+}
+
+// expectedSequences lists the sequences used for generated ids
+var expectedSequences = []string{"seq_history", "seq_messages", "seq_ledger_entries", "seq_ledger_transfers"}
+
+// verifySchema checks the live database against the expected schema, so a
+// bad changelog surfaces on startup instead of on the first query using it
+func verifySchema() error {
+	rows, err := duckdbClient.Query(`
+		SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = 'main';
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		columns[table] = append(columns[table], column)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var problems []string
+	for table, expected := range expectedSchema {
+		live, ok := columns[table]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing table %s", table))
+			continue
+		}
+		for _, column := range expected {
+			if !slices.Contains(live, column) {
+				problems = append(problems, fmt.Sprintf("missing column %s.%s", table, column))
+			}
+		}
+	}
+
+	for _, sequence := range expectedSequences {
+		var count int
+		err := duckdbClient.QueryRow(`SELECT COUNT(*) FROM duckdb_sequences() WHERE sequence_name = ?;`, sequence).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to read sequences: %w", err)
+		}
+		if count == 0 {
+			problems = append(problems, fmt.Sprintf("missing sequence %s", sequence))
+		}
+	}
+
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("database schema does not match: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -1,26 +1,262 @@
 package dmlistener
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
+	"strings"
 
+	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
 )
 
-var (
-	OllamaClient *ollamaApi.Client
-)
+const helpText = "**Direct message commands**\n" +
+	"`reset` start a new conversation\n" +
+	"`!model <name>` set your default model\n" +
+	"`!system <prompt>` set your system prompt, leave empty to clear it\n" +
+	"`!settings` show your current settings\n" +
+	"`!stop` stop the answer being written"
+
+func Listener(event *events.DMMessageCreate) {
+	if event.Message.Author.ID == event.Client().ID() || event.Message.Author.Bot {
+		return
+	}
+
+	userID := event.Message.Author.ID.String()
+	conversationID := event.ChannelID.String()
+
+	settings, err := database.GetDMSettings(userID)
+	if err != nil && err != sql.ErrNoRows {
+		slog.Error("Error fetching dm settings:", slog.Any("err", err))
+	}
+
+	command, args, _ := strings.Cut(strings.TrimSpace(event.Message.Content), " ")
+	switch strings.ToLower(command) {
+	case "reset", "!reset":
+		if err := database.ClearMessages(conversationID); err != nil {
+			slog.Error("Error clearing conversation:", slog.Any("err", err))
+			reply(event, "Failed to reset the conversation")
+			return
+		}
+		reply(event, "Started a new conversation")
+		return
+	case "!model":
+		modelHandler(event, settings, strings.TrimSpace(args))
+		return
+	case "!system":
+		settings.SystemPrompt = strings.TrimSpace(args)
+		if err := database.SetDMSettings(settings); err != nil {
+			slog.Error("Error saving dm settings:", slog.Any("err", err))
+			reply(event, "Failed to save the system prompt")
+			return
+		}
+		reply(event, "Updated your system prompt")
+		return
+	case "!settings":
+		reply(event, fmt.Sprintf("**Model:** %s\n**System prompt:** %s", resolveModel(settings), settings.SystemPrompt))
+		return
+	case "!help":
+		reply(event, helpText)
+		return
+	case "!stop":
+		if ollama.StopChannel(conversationID, userID) == 0 {
+			reply(event, "Nothing is being answered at the moment")
+		}
+		return
+	}
+
+	model := resolveModel(settings)
+	if model == "" {
+		reply(event, "No models are available at the moment")
+		return
+	}
+
+	err = database.CheckQuota(userID, model)
+	if err == database.ErrInsufficientBalance {
+		reply(event, fmt.Sprintf("You don't have enough coins left to use %s, check /list for the prices", model))
+		return
+	} else if err != nil {
+		slog.Error("Error checking quota:", slog.Any("err", err))
+		reply(event, "Something went wrong while checking your balance")
+		return
+	}
+
+	images, err := ollama.MessageImages(model, event.Message)
+	if err == ollama.ErrNoVision {
+		reply(event, fmt.Sprintf("%s can't look at images, send your message as text or switch to a vision model with `!model`", model))
+		return
+	} else if err != nil {
+		slog.Error("Error fetching images:", slog.Any("err", err))
+		reply(event, "Something went wrong while downloading your images")
+		return
+	}
 
-func init() {
-	client, err := ollamaApi.ClientFromEnvironment()
+	reference := discord.MessageReference{
+		MessageID: &event.MessageID,
+		ChannelID: &event.ChannelID,
+	}
+
+	ctx, done := ollama.NewRequest(context.Background(), event.MessageID.String(), conversationID, userID)
+	defer done()
+
+	queued, dequeued := util.NewQueueReply(event.Client().Rest, reference)
+	release, err := ollama.Queue.Acquire(ctx, model, userID, func(position int) {
+		queued(ollama.QueueMessage(model, position))
+	})
+	dequeued()
+	if err != nil {
+		reply(event, ollama.StopReason(err))
+		return
+	}
+	defer release()
+
+	event.Client().Rest.SendTyping(event.ChannelID)
+
+	err = database.AddHistory(database.History{
+		ModelName: model,
+		UserID:    userID,
+		Prompt:    event.Message.Content,
+	})
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("Error saving history: ", slog.Any("err", err))
+	}
+
+	history, err := database.GetMessages(conversationID)
+	if err != nil {
+		slog.Error("Error fetching conversation:", slog.Any("err", err))
+	}
+
+	userMessage := database.Message{
+		ConversationID: conversationID,
+		Role:           "user",
+		Content:        event.Message.Content,
+		ModelName:      model,
+	}
+
+	var messages []ollamaApi.Message
+	if settings.SystemPrompt != "" {
+		messages = append(messages, ollamaApi.Message{Role: "system", Content: settings.SystemPrompt})
+	}
+	messages = append(messages, ollama.ChatMessages(append(history, userMessage))...)
+	messages[len(messages)-1].Images = images
+
+	stream := util.NewReplyStream(event.Client().Rest, reference, event.MessageID.String())
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	req := &ollamaApi.ChatRequest{
+		Model:    model,
+		Messages: messages,
+		Options:  ollama.Options(model),
+	}
+	usage := ollama.NewUsage(req)
+	err = ollama.Client.Chat(ctx, req, usage.Count(func(cr ollamaApi.ChatResponse) error {
+		if err := stream.Write(cr.Message.Content); err != nil || !cr.Done {
+			return err
+		}
+		if err := stream.Close(); err != nil {
+			return err
+		}
+
+		err := database.AddMessages(userMessage, database.Message{
+			ConversationID: conversationID,
+			Role:           "assistant",
+			Content:        stream.String(),
+			ModelName:      model,
+		})
+		if err != nil {
+			slog.Error("Error updating conversation:", slog.Any("err", err))
+		}
+		return nil
+	}))
+	usage.Charge(userID, err)
+	if reason := ollama.StopReason(err); reason != "" {
+		stream.Write("\n\n-# " + reason)
+		stream.Close()
+	} else if err != nil {
+		slog.Error("Error generating response:", slog.Any("err", err))
+		// the partial answer stays, without its Stop button
+		if stream.String() != "" {
+			stream.Close()
+		}
+		reply(event, "Something went wrong while generating a response")
 	}
-	OllamaClient = client
 }
 
-func Listener(dm *events.DMMessageCreate) {
-	channel, _ := dm.Client().Rest.GetChannel(dm.ChannelID)
-	fmt.Println(channel)
+func modelHandler(event *events.DMMessageCreate, settings database.DMSettings, model string) {
+	models, err := database.ListChatModels()
+	if err != nil {
+		slog.Error("Error listing models:", slog.Any("err", err))
+		reply(event, "Failed to list the models")
+		return
+	}
+
+	if model == "" {
+		var names []string
+		for _, m := range models {
+			names = append(names, m.Name)
+		}
+		reply(event, fmt.Sprintf("**Current model:** %s\n**Available models:** %s", resolveModel(settings), strings.Join(names, ", ")))
+		return
+	}
+
+	if !hasModel(models, model) {
+		reply(event, fmt.Sprintf("Model %s is not available, use `!model` to see the available models", model))
+		return
+	}
+
+	settings.ModelName = model
+	if err := database.SetDMSettings(settings); err != nil {
+		slog.Error("Error saving dm settings:", slog.Any("err", err))
+		reply(event, "Failed to save the model")
+		return
+	}
+	reply(event, fmt.Sprintf("Now using %s", model))
+}
+
+// resolveModel returns the users model, falling back to DM_DEFAULT_MODEL and
+// then the first available model. Only priced models that can chat are used.
+func resolveModel(settings database.DMSettings) string {
+	models, err := database.ListChatModels()
+	if err != nil {
+		slog.Error("Error listing models:", slog.Any("err", err))
+		return ""
+	}
+
+	for _, model := range []string{settings.ModelName, util.ConfigFile.DM_DEFAULT_MODEL} {
+		if model != "" && hasModel(models, model) {
+			return model
+		}
+	}
+	if len(models) == 0 {
+		return ""
+	}
+	return models[0].Name
+}
+
+// hasModel reports if the model is one of models
+func hasModel(models []database.Model, model string) bool {
+	return slices.ContainsFunc(models, func(m database.Model) bool {
+		return m.Name == model
+	})
+}
+
+func reply(event *events.DMMessageCreate, content string) {
+	_, err := event.Client().Rest.CreateMessage(event.ChannelID, discord.MessageCreate{
+		MessageReference: &discord.MessageReference{
+			MessageID: &event.MessageID,
+			ChannelID: &event.ChannelID,
+		},
+		Content: content,
+	})
+	if err != nil {
+		slog.Error("Error sending the response:", slog.Any("err", err))
+	}
 }
@@ -3,28 +3,21 @@ package threadlistener
 import (
 	"context"
 	"database/sql"
-	"log"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	ollamaApi "github.com/ollama/ollama/api"
 	"github.com/stollenaar/ollamabot/internal/database"
 	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+	"github.com/stollenaar/ollamabot/internal/util/retrieval"
+	"github.com/stollenaar/ollamabot/internal/util/tools"
 )
 
-var (
-	OllamaClient *ollamaApi.Client
-)
-
-func init() {
-	client, err := ollamaApi.ClientFromEnvironment()
-	if err != nil {
-		log.Fatal(err)
-	}
-	OllamaClient = client
-}
-
 func Listener(event *events.GuildMessageCreate) {
 	if event.Message.Author.ID == event.Client().ID() {
 		return
@@ -40,32 +33,238 @@ func Listener(event *events.GuildMessageCreate) {
 		}
 	}
 
+	command, args, _ := strings.Cut(strings.TrimSpace(event.Message.Content), " ")
+	switch strings.ToLower(command) {
+	case "!options":
+		optionsHandler(event, thread, args)
+		return
+	case "!settings":
+		options := ollama.FormatOptions(ollama.Options(thread.ModelName, thread.Options))
+		if options == "" {
+			options = "Ollama defaults"
+		}
+		toolsSetting := "off"
+		if thread.Tools {
+			toolsSetting = "on"
+		}
+		settings := fmt.Sprintf("**Model:** %s\n**System prompt:** %s\n**Options:** `%s`\n**Tools:** %s", thread.ModelName, thread.Prompt, options, toolsSetting)
+		if thread.KnowledgeBase != "" {
+			settings += fmt.Sprintf("\n**Knowledge base:** %s", thread.KnowledgeBase)
+		}
+		reply(event, settings)
+		return
+	case "!tools":
+		toolsHandler(event, thread, args)
+		return
+	case "!stop":
+		if ollama.StopChannel(event.ChannelID.String(), event.Message.Author.ID.String()) == 0 {
+			reply(event, "Nothing you asked is being answered in this thread")
+		}
+		return
+	}
+
+	err = database.CheckQuota(event.Message.Author.ID.String(), thread.ModelName)
+	if err != nil {
+		content := "Something went wrong while checking your balance"
+		if err == database.ErrInsufficientBalance {
+			content = fmt.Sprintf("You don't have enough coins left to use %s, check /list for the prices", thread.ModelName)
+		} else {
+			slog.Error("Error checking quota:", slog.Any("err", err))
+		}
+
+		reply(event, content)
+		return
+	}
+
+	images, err := ollama.MessageImages(thread.ModelName, event.Message)
+	if err == ollama.ErrNoVision {
+		reply(event, fmt.Sprintf("%s can't look at images, send your message as text or start a thread with a vision model", thread.ModelName))
+		return
+	} else if err != nil {
+		slog.Error("Error fetching images:", slog.Any("err", err))
+		reply(event, "Something went wrong while downloading your images")
+		return
+	}
+
+	reference := discord.MessageReference{
+		MessageID: &event.MessageID,
+		ChannelID: &event.ChannelID,
+		GuildID:   &event.GuildID,
+	}
+
+	ctx, done := ollama.NewRequest(context.Background(), event.MessageID.String(), event.ChannelID.String(), event.Message.Author.ID.String())
+	defer done()
+
+	var sources []retrieval.Source
+	if thread.KnowledgeBase != "" {
+		sources, err = retrieval.SearchKnowledgeBase(ctx, event.Message.Author.ID.String(), thread.KnowledgeBase, event.Message.Content)
+		if reason := ollama.StopReason(err); reason != "" {
+			reply(event, reason)
+			return
+		} else if err != nil {
+			slog.Error("Error searching the knowledge base:", slog.String("knowledge_base", thread.KnowledgeBase), slog.Any("err", err))
+		}
+	}
+
+	queued, dequeued := util.NewQueueReply(event.Client().Rest, reference)
+	release, err := ollama.Queue.Acquire(ctx, thread.ModelName, event.Message.Author.ID.String(), func(position int) {
+		queued(ollama.QueueMessage(thread.ModelName, position))
+	})
+	dequeued()
+	if err != nil {
+		reply(event, ollama.StopReason(err))
+		return
+	}
+	defer release()
+
 	event.Client().Rest.SendTyping(event.ChannelID)
 
-	OllamaClient.Generate(context.TODO(), &ollamaApi.GenerateRequest{
-		Model:   thread.ModelName,
-		System:  thread.Prompt,
-		Prompt:  event.Message.Content,
-		Stream:  new(bool),
-		Context: util.Int32ToIntSlice(thread.Context),
-	}, func(gr ollamaApi.GenerateResponse) error {
-		_, err := event.Client().Rest.CreateMessage(event.ChannelID, discord.MessageCreate{
-			MessageReference: &discord.MessageReference{
-				MessageID: &event.MessageID,
-				ChannelID: &event.ChannelID,
-				GuildID:   &event.GuildID,
-			},
-			Content: gr.Response,
-		})
-
-		if err != nil {
-			slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", gr.Response))
+	history, err := database.GetMessages(thread.ThreadID)
+	if err != nil {
+		slog.Error("Error fetching conversation:", slog.Any("err", err))
+	}
+
+	userMessage := database.Message{
+		ConversationID: thread.ThreadID,
+		Role:           "user",
+		Content:        event.Message.Content,
+		ModelName:      thread.ModelName,
+	}
+
+	stream := util.NewReplyStream(event.Client().Rest, reference, event.MessageID.String())
+
+	messages := ollama.ChatMessages(append(history, userMessage))
+	messages[len(messages)-1].Images = images
+	var citations string
+	if len(sources) > 0 {
+		messages = slices.Insert(messages, len(messages)-1, ollamaApi.Message{Role: "system", Content: retrieval.SystemPrompt(sources)})
+		citations = retrieval.Citations(sources)
+	}
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	req := &ollamaApi.ChatRequest{
+		Model:    thread.ModelName,
+		Messages: messages,
+		Options:  ollama.Options(thread.ModelName, thread.Options),
+	}
+	usage := ollama.NewUsage(req)
+	respond := usage.Count(func(cr ollamaApi.ChatResponse) error {
+		if err := stream.Write(cr.Message.Content); err != nil || !cr.Done {
+			return err
+		}
+		stream.Write(citations)
+		return stream.Close()
+	})
+
+	var toolMessages []ollamaApi.Message
+	if thread.Tools {
+		toolMessages, err = ollama.ChatWithTools(ctx, req, tools.Env{
+			Client:  event.Client().Rest,
+			GuildID: event.GuildID,
+			UserID:  event.Message.Author.ID,
+		}, respond)
+	} else {
+		err = ollama.Client.Chat(ctx, req, respond)
+	}
+	usage.Charge(event.Message.Author.ID.String(), err)
+	if reason := ollama.StopReason(err); reason != "" {
+		stream.Write("\n\n-# " + reason)
+		stream.Close()
+		return
+	} else if err != nil {
+		slog.Error("Error generating response:", slog.Any("err", err))
+		// the partial answer stays, without its Stop button
+		if stream.String() != "" {
+			stream.Close()
 		}
+		reply(event, "Something went wrong while generating a response")
+		return
+	}
+
+	// The stream also holds what the model wrote before calling tools, which
+	// is stored on the assistant messages of those rounds
+	answer := strings.TrimSuffix(stream.String(), citations)
+	for _, message := range toolMessages {
+		if message.Role == "assistant" {
+			answer = strings.TrimPrefix(answer, message.Content)
+		}
+	}
+
+	conversation := append([]database.Message{userMessage}, ollama.HistoryMessages(thread.ThreadID, thread.ModelName, toolMessages)...)
+	err = database.AddMessages(append(conversation, database.Message{
+		ConversationID: thread.ThreadID,
+		Role:           "assistant",
+		Content:        answer,
+		ModelName:      thread.ModelName,
+	})...)
+	if err != nil {
+		slog.Error("Error updating conversation:", slog.Any("err", err))
+	}
+}
+
+// toolsHandler turns the tools of the thread on or off
+func toolsHandler(event *events.GuildMessageCreate, thread database.Thread, args string) {
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		reply(event, "Use `!tools on` or `!tools off`")
+		return
+	}
+
+	if err := database.SetThreadTools(thread.ThreadID, enabled); err != nil {
+		slog.Error("Error saving thread tools:", slog.Any("err", err))
+		reply(event, "Failed to save the tools setting")
+		return
+	}
 
-		err = database.UpdateThreadContext(thread.ThreadID, util.IntToInt32Slice(gr.Context))
-		if err != nil {
-			slog.Error("Error updating context:", slog.Any("err", err))
+	content := "The model can no longer use tools in this thread"
+	if enabled {
+		content = "The model can use tools in this thread"
+		if capable, err := database.ModelSupportsTools(thread.ModelName); err == nil && !capable {
+			content += fmt.Sprintf(", but %s doesn't support tools", thread.ModelName)
 		}
-		return err
+	}
+	reply(event, content)
+}
+
+// optionsHandler sets the generation options of the thread, empty options
+// go back to the model defaults
+func optionsHandler(event *events.GuildMessageCreate, thread database.Thread, args string) {
+	options, err := ollama.ParseOptions(args)
+	if err != nil {
+		reply(event, fmt.Sprintf("Invalid options: %s", err))
+		return
+	}
+
+	if err := database.SetThreadOptions(thread.ThreadID, options); err != nil {
+		slog.Error("Error saving thread options:", slog.Any("err", err))
+		reply(event, "Failed to save the options")
+		return
+	}
+
+	if len(options) == 0 {
+		reply(event, "The thread now uses the model defaults")
+		return
+	}
+	reply(event, fmt.Sprintf("Updated the options to `%s`", ollama.FormatOptions(options)))
+}
+
+func reply(event *events.GuildMessageCreate, content string) {
+	_, err := event.Client().Rest.CreateMessage(event.ChannelID, discord.MessageCreate{
+		MessageReference: &discord.MessageReference{
+			MessageID: &event.MessageID,
+			ChannelID: &event.ChannelID,
+			GuildID:   &event.GuildID,
+		},
+		Content: content,
 	})
+	if err != nil {
+		slog.Error("Error sending the response:", slog.Any("err", err))
+	}
 }
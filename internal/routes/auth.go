@@ -0,0 +1,61 @@
+package routes
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stollenaar/ollamabot/internal/database"
+)
+
+// Context keys holding the owner of the authenticated key
+const (
+	platformKey = "platform_id"
+	userKey     = "user_id"
+)
+
+// APIKeyAuth authenticates the request with an api key. The key is read from
+// a bearer token or from the password of basic auth, in which case the
+// username has to be the platform or user id the key belongs to.
+func APIKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var key, username string
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			key = strings.TrimSpace(token)
+		} else if user, password, ok := c.Request.BasicAuth(); ok {
+			key, username = password, user
+		}
+
+		if key == "" {
+			c.Header("WWW-Authenticate", `Basic realm="ollamabot"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing api key"})
+			return
+		}
+
+		apiKey, err := database.AuthenticateAPIKey(key)
+		if err != nil && err != database.ErrInvalidAPIKey {
+			slog.Error("Error authenticating api key: ", slog.Any("err", err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+			return
+		}
+		if err != nil || (username != "" && username != apiKey.PlatformID && username != apiKey.UserID) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid api key"})
+			return
+		}
+
+		c.Set(platformKey, apiKey.PlatformID)
+		c.Set(userKey, apiKey.UserID)
+		c.Next()
+	}
+}
+
+// PlatformID returns the platform the request is authenticated for
+func PlatformID(c *gin.Context) string {
+	return c.GetString(platformKey)
+}
+
+// UserID returns the user the request is authenticated for, empty for platform keys
+func UserID(c *gin.Context) string {
+	return c.GetString(userKey)
+}
@@ -0,0 +1,397 @@
+package routes
+
+import (
+	"crypto/rand"
+	b64 "encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+const (
+	// maxRequestSize caps the body of a chat completion, base64 images included
+	maxRequestSize = 32 << 20
+	// maxImageSize caps each decoded image of a chat completion
+	maxImageSize = 10 << 20
+)
+
+// The request and response bodies follow the OpenAI API, only the fields
+// the bot supports are mapped.
+type openAIContentPart struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+type openAIMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model         string          `json:"model" binding:"required"`
+	Messages      []openAIMessage `json:"messages" binding:"required"`
+	Stream        bool            `json:"stream"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+	Temperature *float64 `json:"temperature"`
+	TopP        *float64 `json:"top_p"`
+	Seed        *int     `json:"seed"`
+	Stop        any      `json:"stop"`
+	MaxTokens   *int     `json:"max_tokens"`
+}
+
+type openAIChoice struct {
+	Index        int                 `json:"index"`
+	Message      *openAIReplyMessage `json:"message,omitempty"`
+	Delta        *openAIReplyMessage `json:"delta,omitempty"`
+	FinishReason *string             `json:"finish_reason"`
+}
+
+type openAIReplyMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIChatResponse struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage,omitempty"`
+}
+
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// RegisterOpenAIRoutes registers the OpenAI compatible routes, so existing
+// OpenAI clients can use the models of the bot with a user api key.
+func RegisterOpenAIRoutes(r *gin.Engine) {
+	v1 := r.Group("/v1", APIKeyAuth(), requireUserKey)
+	{
+		v1.GET("/models", ListOpenAIModels)
+		v1.POST("/chat/completions", ChatCompletions)
+	}
+}
+
+// requireUserKey rejects platform keys, the OpenAI routes spend the coins of a user
+func requireUserKey(c *gin.Context) {
+	if UserID(c) == "" {
+		openAIError(c, http.StatusForbidden, "permission_error", "A user api key is required, create one with /apikey in discord")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// ListOpenAIModels returns the models added to the bot
+func ListOpenAIModels(c *gin.Context) {
+	models, err := database.ListModels()
+	if err != nil {
+		slog.Error("Error listing models: ", slog.Any("err", err))
+		openAIError(c, http.StatusInternalServerError, "server_error", "Failed to list the models")
+		return
+	}
+
+	data := []openAIModel{}
+	for _, model := range models {
+		data = append(data, openAIModel{
+			ID:      model,
+			Object:  "model",
+			OwnedBy: "ollamabot",
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": data})
+}
+
+// ChatCompletions forwards the conversation to the backend of the model and
+// charges the used tokens to the user of the api key
+func ChatCompletions(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestSize)
+
+	var req openAIChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			openAIError(c, http.StatusRequestEntityTooLarge, "invalid_request_error", fmt.Sprintf("The request is larger than %d MiB", maxRequestSize>>20))
+			return
+		}
+		openAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	models, err := database.ListModels()
+	if err != nil {
+		slog.Error("Error listing models: ", slog.Any("err", err))
+		openAIError(c, http.StatusInternalServerError, "server_error", "Failed to list the models")
+		return
+	}
+	if !slices.Contains(models, req.Model) {
+		openAIError(c, http.StatusNotFound, "model_not_found", fmt.Sprintf("The model %s does not exist", req.Model))
+		return
+	}
+
+	userID := UserID(c)
+	err = database.CheckQuota(userID, req.Model)
+	if err == database.ErrInsufficientBalance {
+		openAIError(c, http.StatusPaymentRequired, "insufficient_quota", fmt.Sprintf("You don't have enough coins left to use %s", req.Model))
+		return
+	} else if err != nil {
+		slog.Error("Error checking quota: ", slog.Any("err", err))
+		openAIError(c, http.StatusInternalServerError, "server_error", "Failed to check the balance")
+		return
+	}
+
+	messages, err := chatMessages(req.Messages)
+	if err != nil {
+		openAIError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	for _, message := range messages {
+		if len(message.Images) == 0 {
+			continue
+		}
+		vision, err := database.ModelSupportsVision(req.Model)
+		if err != nil {
+			slog.Error("Error fetching model vision: ", slog.Any("err", err))
+			openAIError(c, http.StatusInternalServerError, "server_error", "Failed to check the model")
+			return
+		}
+		if !vision {
+			openAIError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("The model %s does not support images", req.Model))
+			return
+		}
+		break
+	}
+
+	chatReq := &ollamaApi.ChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   &req.Stream,
+		Options:  ollama.Options(req.Model, chatOptions(req)),
+	}
+
+	id := completionID()
+	created := time.Now().Unix()
+	var content strings.Builder
+	var done ollamaApi.ChatResponse
+
+	ctx, requestDone := ollama.NewRequest(c.Request.Context(), id, "", userID)
+	defer requestDone()
+
+	release, err := ollama.Queue.Acquire(ctx, req.Model, userID, nil)
+	if err != nil {
+		openAIError(c, http.StatusServiceUnavailable, "server_error", "The request was cancelled while waiting in the queue")
+		return
+	}
+	defer release()
+
+	ctx, cancel := ollama.WithGenerationTimeout(ctx)
+	defer cancel()
+
+	if req.Stream {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Status(http.StatusOK)
+	}
+
+	// The usage is charged whatever ended the chat, also when the client
+	// went away before the done response was written
+	used := ollama.NewUsage(chatReq)
+	err = ollama.Client.Chat(ctx, chatReq, used.Count(func(cr ollamaApi.ChatResponse) error {
+		content.WriteString(cr.Message.Content)
+		if cr.Done {
+			done = cr
+		}
+		if !req.Stream {
+			return nil
+		}
+
+		chunk := openAIChatResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   req.Model,
+			Choices: []openAIChoice{{
+				Delta: &openAIReplyMessage{Role: "assistant", Content: cr.Message.Content},
+			}},
+		}
+		if cr.Done {
+			chunk.Choices[0].FinishReason = finishReason(cr.DoneReason)
+		}
+		if err := writeEvent(c, chunk); err != nil {
+			return err
+		}
+		if cr.Done && req.StreamOptions.IncludeUsage {
+			chunk.Choices = []openAIChoice{}
+			chunk.Usage = usage(cr)
+			return writeEvent(c, chunk)
+		}
+		return nil
+	}))
+	used.Charge(userID, err)
+	if err != nil {
+		slog.Error("Error generating response: ", slog.Any("err", err))
+		if req.Stream {
+			// The status is already sent, report the error as the last event
+			writeEvent(c, gin.H{"error": gin.H{"message": "Something went wrong while generating a response", "type": "server_error"}})
+			return
+		}
+		openAIError(c, http.StatusBadGateway, "server_error", "Something went wrong while generating a response")
+		return
+	}
+
+	if req.Stream {
+		fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+		c.Writer.Flush()
+		return
+	}
+
+	c.JSON(http.StatusOK, openAIChatResponse{
+		ID:      id,
+		Object:  "chat.completion",
+		Created: created,
+		Model:   req.Model,
+		Choices: []openAIChoice{{
+			Message:      &openAIReplyMessage{Role: "assistant", Content: content.String()},
+			FinishReason: finishReason(done.DoneReason),
+		}},
+		Usage: usage(done),
+	})
+}
+
+// chatMessages converts the OpenAI messages, the content is either a string
+// or a list of text and base64 image parts
+func chatMessages(messages []openAIMessage) (result []ollamaApi.Message, err error) {
+	for _, message := range messages {
+		converted := ollamaApi.Message{Role: message.Role}
+
+		var text string
+		if err := json.Unmarshal(message.Content, &text); err == nil {
+			converted.Content = text
+			result = append(result, converted)
+			continue
+		}
+
+		var parts []openAIContentPart
+		if err := json.Unmarshal(message.Content, &parts); err != nil {
+			return nil, fmt.Errorf("invalid content for %s message", message.Role)
+		}
+		for _, part := range parts {
+			switch part.Type {
+			case "text":
+				converted.Content += part.Text
+			case "image_url":
+				_, data, ok := strings.Cut(part.ImageURL.URL, ";base64,")
+				if !ok {
+					return nil, fmt.Errorf("only base64 data urls are supported for images")
+				}
+				if b64.StdEncoding.DecodedLen(len(data)) > maxImageSize {
+					return nil, fmt.Errorf("images can be at most %d MiB", maxImageSize>>20)
+				}
+				image, err := b64.StdEncoding.DecodeString(data)
+				if err != nil {
+					return nil, fmt.Errorf("invalid base64 image: %w", err)
+				}
+				converted.Images = append(converted.Images, image)
+			default:
+				return nil, fmt.Errorf("unsupported content part %s", part.Type)
+			}
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+// chatOptions maps the OpenAI sampling parameters onto the Ollama options
+func chatOptions(req openAIChatRequest) map[string]any {
+	options := make(map[string]any)
+	if req.Temperature != nil {
+		options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		options["top_p"] = *req.TopP
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	if req.MaxTokens != nil {
+		options["num_predict"] = *req.MaxTokens
+	}
+	switch stop := req.Stop.(type) {
+	case string:
+		options["stop"] = []string{stop}
+	case []any:
+		options["stop"] = stop
+	}
+	return options
+}
+
+func finishReason(doneReason string) *string {
+	reason := "stop"
+	if doneReason == "length" {
+		reason = "length"
+	}
+	return &reason
+}
+
+func usage(cr ollamaApi.ChatResponse) *openAIUsage {
+	return &openAIUsage{
+		PromptTokens:     cr.PromptEvalCount,
+		CompletionTokens: cr.EvalCount,
+		TotalTokens:      cr.PromptEvalCount + cr.EvalCount,
+	}
+}
+
+func writeEvent(c *gin.Context, event any) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+func completionID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "chatcmpl-" + hex.EncodeToString(b)
+}
+
+// openAIError writes an error in the format OpenAI clients expect
+func openAIError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{"error": gin.H{
+		"message": message,
+		"type":    errType,
+	}})
+}
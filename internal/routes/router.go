@@ -43,6 +43,8 @@ func CreateRouter() {
 		RegisterTradeRoutes(v1)
 	}
 
+	RegisterOpenAIRoutes(r)
+
 	// Swagger UI endpoint
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	r.Run()
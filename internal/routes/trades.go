@@ -8,8 +8,9 @@ import (
 )
 
 // RegisterTradeRoutes registers trade-related routes to the given router group.
+// Every trade route requires an api key and only sees the trades of its platform.
 func RegisterTradeRoutes(rg *gin.RouterGroup) {
-	trades := rg.Group("/trades")
+	trades := rg.Group("/trades", APIKeyAuth())
 	{
 		trades.GET("/:id", GetTrade)
 		trades.GET("/platform/:id", ListTrades)
@@ -25,12 +26,14 @@ func RegisterTradeRoutes(rg *gin.RouterGroup) {
 //	@Produce		json
 //	@Param			id	path		string	true	"Trade ID"
 //	@Success		200	{object}	database.Transaction
+//	@Failure		401	{object}	map[string]string
 //	@Failure		404	{object}	map[string]string
+//	@Security		BasicAuth
 //	@Router			/trades/{id} [get]
 func GetTrade(c *gin.Context) {
 	id := c.Param("id")
 	tx, err := database.GetTransactionByID(id)
-	if err != nil {
+	if err != nil || tx.PlatformID != PlatformID(c) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
 		return
 	}
@@ -45,10 +48,17 @@ func GetTrade(c *gin.Context) {
 //	@Produce		json
 //	@Param			id	path		string	true	"Platform ID"
 //	@Success		200	{array}		database.Transaction
+//	@Failure		401	{object}	map[string]string
+//	@Failure		403	{object}	map[string]string
 //	@Failure		500	{object}	map[string]string
+//	@Security		BasicAuth
 //	@Router			/trades/platform/{id} [get]
 func ListTrades(c *gin.Context) {
 	id := c.Param("id")
+	if id != PlatformID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Api key is not valid for this platform"})
+		return
+	}
 	tx, err := database.GetTransactionByPlatformID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transactions not found"})
@@ -61,7 +71,7 @@ func ListTrades(c *gin.Context) {
 // UpdateTrade updates the status of a transaction by ID.
 //
 //	@Summary		Update trade status
-//	@Description	Update the status of a trade by ID and platform ID
+//	@Description	Update the status of a trade by ID and platform ID, approving a trade credits the user
 //	@Tags			trades
 //	@Accept			json
 //	@Produce		json
@@ -69,8 +79,12 @@ func ListTrades(c *gin.Context) {
 //	@Param			body	body		routes.UpdateTrade.request	true	"Update payload"
 //	@Success		200		{object}	map[string]string
 //	@Failure		400		{object}	map[string]string
+//	@Failure		401		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
 //	@Failure		404		{object}	map[string]string
+//	@Failure		409		{object}	map[string]string
 //	@Failure		500		{object}	map[string]string
+//	@Security		BasicAuth
 //	@Router			/trades/{id} [post]
 func UpdateTrade(c *gin.Context) {
 	id := c.Param("id")
@@ -85,6 +99,11 @@ func UpdateTrade(c *gin.Context) {
 		return
 	}
 
+	if req.PlatformID != PlatformID(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Api key is not valid for this platform"})
+		return
+	}
+
 	tx, err := database.GetTransactionByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Transaction not found"})
@@ -96,8 +115,18 @@ func UpdateTrade(c *gin.Context) {
 		return
 	}
 
-	tx.Status = req.Status
-	if err := database.UpdateTransaction(*tx); err != nil {
+	switch req.Status {
+	case database.TransactionPending, database.TransactionApproved, database.TransactionRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	err = database.UpdateTransactionStatus(id, req.Status)
+	if err == database.ErrTransactionFinal {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transaction"})
 		return
 	}
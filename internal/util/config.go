@@ -20,18 +20,39 @@ type Config struct {
 	DUCKDB_PATH        string
 	AWS_PARAMETER_NAME string
 
-	AWS_REGION     string
-	TERMINAL_REGEX string
+	AWS_REGION           string
+	TERMINAL_REGEX       string
+	STREAM_EDIT_INTERVAL string
 
+	OVERFLOW_MAX_LENGTH string
+	OVERFLOW_CODE_LINES string
+
+	LLM_BACKEND      string
 	OLLAMA_URL       string
 	OLLAMA_AUTH_TYPE string
 
+	OLLAMA_HOSTS         string
+	OLLAMA_POLL_INTERVAL string
+
+	QUEUE_CONCURRENCY       string
+	QUEUE_MODEL_CONCURRENCY string
+	GENERATION_TIMEOUT      string
+
+	EMBEDDING_MODEL string
+	RETRIEVAL_TOP_K string
+
+	OPENAI_BASE_URL string
+	OPENAI_API_KEY  string
+	OPENAI_MODELS   string
+
 	AWS_OLLAMA_AUTH_USERNAME string
 	OLLAMA_AUTH_USERNAME     string
 	AWS_OLLAMA_AUTH_PASSWORD string
 	OLLAMA_AUTH_PASSWORD     string
 
-	ADMIN_USER_ID string
+	ADMIN_USER_ID      string
+	DM_DEFAULT_MODEL   string
+	CONTEXT_MENU_MODEL string
 }
 
 var (
@@ -54,18 +75,64 @@ func init() {
 		DISCORD_TOKEN:            os.Getenv("DISCORD_TOKEN"),
 		AWS_PARAMETER_NAME:       os.Getenv("AWS_PARAMETER_NAME"),
 		TERMINAL_REGEX:           os.Getenv("TERMINAL_REGEX"),
+		STREAM_EDIT_INTERVAL:     os.Getenv("STREAM_EDIT_INTERVAL"),
+		OVERFLOW_MAX_LENGTH:      os.Getenv("OVERFLOW_MAX_LENGTH"),
+		OVERFLOW_CODE_LINES:      os.Getenv("OVERFLOW_CODE_LINES"),
 		DUCKDB_PATH:              os.Getenv("DUCKDB_PATH"),
+		LLM_BACKEND:              os.Getenv("LLM_BACKEND"),
 		OLLAMA_URL:               os.Getenv("OLLAMA_URL"),
 		OLLAMA_AUTH_TYPE:         os.Getenv("OLLAMA_AUTH_TYPE"),
+		OLLAMA_HOSTS:             os.Getenv("OLLAMA_HOSTS"),
+		OLLAMA_POLL_INTERVAL:     os.Getenv("OLLAMA_POLL_INTERVAL"),
+		QUEUE_CONCURRENCY:        os.Getenv("QUEUE_CONCURRENCY"),
+		QUEUE_MODEL_CONCURRENCY:  os.Getenv("QUEUE_MODEL_CONCURRENCY"),
+		GENERATION_TIMEOUT:       os.Getenv("GENERATION_TIMEOUT"),
+		EMBEDDING_MODEL:          os.Getenv("EMBEDDING_MODEL"),
+		RETRIEVAL_TOP_K:          os.Getenv("RETRIEVAL_TOP_K"),
 		OLLAMA_AUTH_USERNAME:     os.Getenv("OLLAMA_AUTH_USERNAME"),
 		OLLAMA_AUTH_PASSWORD:     os.Getenv("OLLAMA_AUTH_PASSWORD"),
 		AWS_OLLAMA_AUTH_USERNAME: os.Getenv("AWS_OLLAMA_AUTH_USERNAME"),
 		AWS_OLLAMA_AUTH_PASSWORD: os.Getenv("AWS_OLLAMA_AUTH_PASSWORD"),
+		OPENAI_BASE_URL:          os.Getenv("OPENAI_BASE_URL"),
+		OPENAI_API_KEY:           os.Getenv("OPENAI_API_KEY"),
+		OPENAI_MODELS:            os.Getenv("OPENAI_MODELS"),
 		ADMIN_USER_ID:            os.Getenv("ADMIN_USER_ID"),
+		DM_DEFAULT_MODEL:         os.Getenv("DM_DEFAULT_MODEL"),
+		CONTEXT_MENU_MODEL:       os.Getenv("CONTEXT_MENU_MODEL"),
 	}
 	if ConfigFile.TERMINAL_REGEX == "" {
 		ConfigFile.TERMINAL_REGEX = `(\.|,|:|;|\?|!)$`
 	}
+	if ConfigFile.STREAM_EDIT_INTERVAL == "" {
+		ConfigFile.STREAM_EDIT_INTERVAL = "1500ms"
+	}
+	if ConfigFile.OVERFLOW_MAX_LENGTH == "" {
+		ConfigFile.OVERFLOW_MAX_LENGTH = "6000"
+	}
+	if ConfigFile.OVERFLOW_CODE_LINES == "" {
+		ConfigFile.OVERFLOW_CODE_LINES = "60"
+	}
+	if ConfigFile.LLM_BACKEND == "" {
+		ConfigFile.LLM_BACKEND = "ollama"
+	}
+	if ConfigFile.OLLAMA_POLL_INTERVAL == "" {
+		ConfigFile.OLLAMA_POLL_INTERVAL = "30s"
+	}
+	if ConfigFile.QUEUE_CONCURRENCY == "" {
+		ConfigFile.QUEUE_CONCURRENCY = "1"
+	}
+	if ConfigFile.GENERATION_TIMEOUT == "" {
+		ConfigFile.GENERATION_TIMEOUT = "5m"
+	}
+	if ConfigFile.EMBEDDING_MODEL == "" {
+		ConfigFile.EMBEDDING_MODEL = "nomic-embed-text"
+	}
+	if ConfigFile.RETRIEVAL_TOP_K == "" {
+		ConfigFile.RETRIEVAL_TOP_K = "5"
+	}
+	if ConfigFile.CONTEXT_MENU_MODEL == "" {
+		ConfigFile.CONTEXT_MENU_MODEL = ConfigFile.DM_DEFAULT_MODEL
+	}
 
 }
 
@@ -0,0 +1,258 @@
+package util
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// codeFence is the shortest marker opening a fenced code block
+const codeFence = "```"
+
+// contentBlock is a paragraph or a whole fenced code block, together with
+// the newlines separating it from the previous block
+type contentBlock struct {
+	sep  string
+	text string
+}
+
+// contentSplitter fills chunks up to maxLength characters, keeping track of
+// the code block the current chunk is in
+type contentSplitter struct {
+	maxLength int
+	chunks    []string
+	current   strings.Builder
+	size      int
+	prefix    int
+	fresh     bool
+	fence     string
+}
+
+// BreakContent splits content into chunks of at most maxLength characters.
+// It breaks between paragraphs first, then between lines and words, and only
+// cuts a word when it doesn't fit in a chunk on its own. A code block split
+// over chunks is closed and reopened with the same language tag.
+func BreakContent(content string, maxLength int) []string {
+	splitter := contentSplitter{maxLength: maxLength, fresh: true}
+	for _, block := range splitBlocks(content) {
+		splitter.addBlock(block)
+	}
+
+	if !splitter.blank() || len(splitter.chunks) == 0 {
+		splitter.chunks = append(splitter.chunks, splitter.current.String())
+	}
+	return splitter.chunks
+}
+
+// splitBlocks splits content into paragraphs, keeping fenced code blocks
+// whole. Runs of blank lines between paragraphs collapse into one.
+func splitBlocks(content string) (blocks []contentBlock) {
+	var lines []string
+	var fence string
+	blank := false
+
+	push := func() {
+		if len(lines) > 0 {
+			sep := "\n"
+			if blank {
+				sep = "\n\n"
+			}
+			blocks = append(blocks, contentBlock{
+				sep:  sep,
+				text: strings.Join(lines, "\n"),
+			})
+			lines, blank = nil, false
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		next := nextFence(fence, line)
+		switch {
+		case fence != "":
+			lines = append(lines, line)
+			if next == "" {
+				push()
+			}
+		case next != "":
+			push()
+			lines = append(lines, line)
+		case strings.TrimSpace(line) == "":
+			push()
+			blank = len(blocks) > 0
+		default:
+			lines = append(lines, line)
+		}
+		fence = next
+	}
+	push()
+	return blocks
+}
+
+// nextFence returns the opening line of the code block that is open after
+// the lines of text. Like in markdown a block is closed by a line of at least
+// as many backticks, and a line holding a whole block like ```code``` opens
+// nothing.
+func nextFence(fence, text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if fence == "" {
+			if strings.HasPrefix(line, codeFence) && !strings.Contains(strings.TrimLeft(line, "`"), codeFence) {
+				fence = line
+			}
+		} else if strings.Trim(line, "`") == "" && len(line) >= len(fenceMarker(fence)) {
+			fence = ""
+		}
+	}
+	return fence
+}
+
+// fenceMarker returns the backticks opening a code block
+func fenceMarker(fence string) string {
+	return fence[:len(fence)-len(strings.TrimLeft(fence, "`"))]
+}
+
+func (s *contentSplitter) addBlock(block contentBlock) {
+	fence := nextFence(s.fence, block.text)
+	if s.fits(s.separator(block.sep)+block.text, fence) {
+		s.write(s.separator(block.sep)+block.text, fence)
+		return
+	}
+
+	// Start a new chunk for a block that fits in one, or for a code block
+	// that needs splitting so it doesn't open at the end of a chunk
+	firstLine, _, _ := strings.Cut(block.text, "\n")
+	if !s.fresh && (utf8.RuneCountInString(block.text)+closingLength(fence) <= s.maxLength || nextFence("", firstLine) != "") {
+		s.flush()
+		if s.fits(block.text, fence) {
+			s.write(block.text, fence)
+			return
+		}
+	}
+
+	for i, line := range strings.Split(block.text, "\n") {
+		sep := "\n"
+		if i == 0 {
+			sep = block.sep
+		}
+		s.addLine(line, sep)
+	}
+}
+
+func (s *contentSplitter) addLine(line, sep string) {
+	fence := nextFence(s.fence, line)
+	if s.fits(s.separator(sep)+line, fence) {
+		s.write(s.separator(sep)+line, fence)
+		return
+	}
+
+	if !s.fresh {
+		s.flush()
+		if s.fits(line, fence) {
+			s.write(line, fence)
+			return
+		}
+	}
+
+	for i, word := range strings.Split(line, " ") {
+		sep := " "
+		if i == 0 {
+			sep = ""
+		}
+		s.addWord(word, sep)
+	}
+	s.fence = fence
+}
+
+func (s *contentSplitter) addWord(word, sep string) {
+	if s.fits(s.separator(sep)+word, s.fence) {
+		s.write(s.separator(sep)+word, s.fence)
+		return
+	}
+	if word == "" {
+		return
+	}
+
+	if !s.fresh {
+		s.flush()
+		if s.fits(word, s.fence) {
+			s.write(word, s.fence)
+			return
+		}
+	}
+
+	runes := []rune(word)
+	for len(runes) > 0 {
+		available := s.maxLength - s.size - closingLength(s.fence)
+		if available < 1 {
+			if !s.fresh {
+				s.flush()
+				continue
+			}
+			available = 1
+		}
+
+		n := min(available, len(runes))
+		s.write(string(runes[:n]), s.fence)
+		runes = runes[n:]
+		if len(runes) > 0 {
+			s.flush()
+		}
+	}
+}
+
+// separator drops the separator at the start of a chunk
+func (s *contentSplitter) separator(sep string) string {
+	if s.fresh {
+		return ""
+	}
+	return sep
+}
+
+// fits reports if text fits in the current chunk, leaving room to close the
+// code block that is open after it
+func (s *contentSplitter) fits(text, fence string) bool {
+	return s.size+utf8.RuneCountInString(text)+closingLength(fence) <= s.maxLength
+}
+
+func (s *contentSplitter) write(text, fence string) {
+	s.current.WriteString(text)
+	s.size += utf8.RuneCountInString(text)
+	s.fresh = false
+	s.fence = fence
+}
+
+// blank reports if nothing but whitespace was added to the current chunk
+func (s *contentSplitter) blank() bool {
+	return strings.TrimSpace(s.current.String()[s.prefix:]) == ""
+}
+
+// flush ends the current chunk, closing the open code block and reopening
+// it in the next chunk. Chunks holding only whitespace are dropped.
+func (s *contentSplitter) flush() {
+	if !s.blank() {
+		chunk := s.current.String()
+		if s.fence != "" {
+			if !strings.HasSuffix(chunk, "\n") {
+				chunk += "\n"
+			}
+			chunk += fenceMarker(s.fence)
+		}
+		s.chunks = append(s.chunks, chunk)
+	}
+
+	s.current.Reset()
+	s.size, s.prefix = 0, 0
+	if s.fence != "" {
+		s.current.WriteString(s.fence + "\n")
+		s.size = utf8.RuneCountInString(s.fence) + 1
+		s.prefix = len(s.fence) + 1
+	}
+	s.fresh = true
+}
+
+// closingLength is the room needed to close the open code block
+func closingLength(fence string) int {
+	if fence == "" {
+		return 0
+	}
+	return len("\n" + fenceMarker(fence))
+}
@@ -0,0 +1,125 @@
+package util
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBreakContent(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		maxLength int
+		want      []string
+	}{
+		{
+			name:      "fits",
+			content:   "hello world",
+			maxLength: 100,
+			want:      []string{"hello world"},
+		},
+		{
+			name:      "empty",
+			content:   "",
+			maxLength: 10,
+			want:      []string{""},
+		},
+		{
+			name:      "exactly at the limit",
+			content:   "abcd efgh",
+			maxLength: 9,
+			want:      []string{"abcd efgh"},
+		},
+		{
+			name:      "one over the limit",
+			content:   "abcd efgh",
+			maxLength: 8,
+			want:      []string{"abcd", "efgh"},
+		},
+		{
+			name:      "word longer than the limit",
+			content:   "abcdefghij",
+			maxLength: 4,
+			want:      []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:      "long word between short ones",
+			content:   "short words then averyveryverylongword end",
+			maxLength: 10,
+			want:      []string{"short", "words then", "averyveryv", "erylongwor", "d end"},
+		},
+		{
+			name:      "prefers paragraphs",
+			content:   "aa\n\nbb cc dd",
+			maxLength: 10,
+			want:      []string{"aa", "bb cc dd"},
+		},
+		{
+			name:      "prefers lines",
+			content:   "aa\nbb cc dd",
+			maxLength: 9,
+			want:      []string{"aa", "bb cc dd"},
+		},
+		{
+			name:      "reopens code block with its language",
+			content:   "```go\nfmt.Println(1)\nfmt.Println(2)\nfmt.Println(3)\n```",
+			maxLength: 32,
+			want: []string{
+				"```go\nfmt.Println(1)\n```",
+				"```go\nfmt.Println(2)\n```",
+				"```go\nfmt.Println(3)\n```",
+			},
+		},
+		{
+			name:      "code block between paragraphs",
+			content:   "intro text\n\n```python\nprint(1)\nprint(2)\n```\n\noutro",
+			maxLength: 30,
+			want: []string{
+				"intro text",
+				"```python\nprint(1)\n```",
+				"```python\nprint(2)\n```\n\noutro",
+			},
+		},
+		{
+			name:      "keeps blank lines in code blocks",
+			content:   "```\na\n\n\nb\n```",
+			maxLength: 100,
+			want:      []string{"```\na\n\n\nb\n```"},
+		},
+		{
+			name:      "keeps a paragraph break",
+			content:   "a\n\nb",
+			maxLength: 100,
+			want:      []string{"a\n\nb"},
+		},
+		// Unlike splitting on spaces alone, runs of blank lines and lines
+		// holding only whitespace collapse into a single blank line
+		{
+			name:      "collapses runs of blank lines",
+			content:   "a\n\n\n\nb",
+			maxLength: 100,
+			want:      []string{"a\n\nb"},
+		},
+		{
+			name:      "collapses whitespace lines",
+			content:   "\n\na\n \n\t\nb\n\n",
+			maxLength: 100,
+			want:      []string{"a\n\nb"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BreakContent(tt.content, tt.maxLength)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("BreakContent(%q, %d) = %q, want %q", tt.content, tt.maxLength, got, tt.want)
+			}
+			for _, chunk := range got {
+				if length := utf8.RuneCountInString(chunk); length > tt.maxLength {
+					t.Errorf("chunk %q is %d characters, over the limit of %d", chunk, length, tt.maxLength)
+				}
+			}
+		})
+	}
+}
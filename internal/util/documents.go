@@ -0,0 +1,56 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+// MaxDocumentSize bounds the size of uploaded documents
+const MaxDocumentSize = 2 << 20
+
+var (
+	// ErrNotText is returned for attachments that don't hold text, like
+	// images or PDFs that weren't exported to text
+	ErrNotText = errors.New("the file doesn't hold text")
+	// ErrDocumentTooLarge is returned for attachments above MaxDocumentSize
+	ErrDocumentTooLarge = fmt.Errorf("the file is larger than %d MiB", MaxDocumentSize>>20)
+)
+
+// FetchTextAttachment downloads an attachment holding text, like markdown,
+// plain text or source code
+func FetchTextAttachment(attachment discord.Attachment) (string, error) {
+	if attachment.Size > MaxDocumentSize {
+		return "", ErrDocumentTooLarge
+	}
+
+	resp, err := http.Get(attachment.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch attachment %s: %w", attachment.Filename, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, attachment.URL)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxDocumentSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read attachment %s: %w", attachment.Filename, err)
+	}
+	if len(data) > MaxDocumentSize {
+		return "", ErrDocumentTooLarge
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) != -1 {
+		return "", ErrNotText
+	}
+
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	return strings.ReplaceAll(text, "\r\n", "\n"), nil
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+// maxEmojiImages limits the custom emoji sent along with a message
+const maxEmojiImages = 5
+
+var emojiRegex = regexp.MustCompile(`<(a?):\w+:(\d+)>`)
+
+// ImageAttachments returns the attachments of a message that are images
+func ImageAttachments(attachments []discord.Attachment) (images []discord.Attachment) {
+	for _, attachment := range attachments {
+		if attachment.ContentType != nil && strings.HasPrefix(*attachment.ContentType, "image/") {
+			images = append(images, attachment)
+		}
+	}
+	return
+}
+
+// FetchAttachmentImages downloads the image attachments
+func FetchAttachmentImages(attachments []discord.Attachment) (images [][]byte, err error) {
+	for _, attachment := range attachments {
+		data, err := fetchImage(attachment.URL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch attachment %s: %w", attachment.Filename, err)
+		}
+		images = append(images, data)
+	}
+	return
+}
+
+// FetchEmojiImages downloads the custom emoji used in the content, each emoji once
+func FetchEmojiImages(content string) (images [][]byte, err error) {
+	seen := make(map[string]bool)
+	for _, match := range emojiRegex.FindAllStringSubmatch(content, -1) {
+		if seen[match[2]] || len(seen) == maxEmojiImages {
+			continue
+		}
+		seen[match[2]] = true
+
+		encoded, err := FetchDiscordEmojiImage(match[2], match[1] == "a")
+		if err != nil {
+			return nil, err
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return
+}
+
+func fetchImage(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image from %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,89 @@
+package util
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// FetchMessages pages back through the messages of a channel sent between
+// since and until, zero times leave that end open. It stops after limit
+// messages and returns them oldest first, progress is told how many messages
+// were read after every page.
+func FetchMessages(ctx context.Context, client rest.Rest, channelID snowflake.ID, limit int, since, until time.Time, progress func(read int)) (messages []discord.Message, err error) {
+	var before snowflake.ID
+	if !until.IsZero() {
+		before = snowflake.New(until)
+	}
+
+	for len(messages) < limit {
+		page, err := client.GetMessages(channelID, 0, before, 0, min(limit-len(messages), 100), rest.WithCtx(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		before = page[len(page)-1].ID
+
+		done := false
+		for _, message := range page {
+			sent, err := SnowflakeToTimestamp(message.ID.String())
+			if err != nil {
+				return nil, err
+			}
+			if !since.IsZero() && sent.Before(since) {
+				done = true
+				break
+			}
+			messages = append(messages, message)
+		}
+		if progress != nil {
+			progress(len(messages))
+		}
+		if done {
+			break
+		}
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// FetchMessagesAfter pages forward through the messages of a channel sent
+// after the message with the id. It stops after limit messages and returns
+// them oldest first, progress is told how many messages were read after
+// every page.
+func FetchMessagesAfter(ctx context.Context, client rest.Rest, channelID, after snowflake.ID, limit int, progress func(read int)) (messages []discord.Message, err error) {
+	for len(messages) < limit {
+		page, err := client.GetMessages(channelID, 0, 0, after, min(limit-len(messages), 100), rest.WithCtx(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if len(page) == 0 {
+			break
+		}
+		// Discord returns the page newest first
+		slices.SortFunc(page, func(a, b discord.Message) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+		messages = append(messages, page...)
+		after = page[len(page)-1].ID
+
+		if progress != nil {
+			progress(len(messages))
+		}
+	}
+	return messages, nil
+}
+
+// TranscriptLine formats a message as a line of a conversation transcript
+// for the models to read
+func TranscriptLine(message discord.Message) string {
+	return fmt.Sprintf("%s (%s): %s", message.Author.EffectiveName(), message.CreatedAt.UTC().Format(time.DateTime), message.Content)
+}
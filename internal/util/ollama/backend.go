@@ -0,0 +1,83 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+// Backend is the set of LLM operations the bot relies on. The Ollama api
+// types are used as the common request/response shapes for every backend.
+type Backend interface {
+	Generate(ctx context.Context, req *ollamaApi.GenerateRequest, fn ollamaApi.GenerateResponseFunc) error
+	Chat(ctx context.Context, req *ollamaApi.ChatRequest, fn ollamaApi.ChatResponseFunc) error
+	List(ctx context.Context) (*ollamaApi.ListResponse, error)
+	Pull(ctx context.Context, req *ollamaApi.PullRequest, fn ollamaApi.PullProgressFunc) error
+	Embed(ctx context.Context, req *ollamaApi.EmbedRequest) (*ollamaApi.EmbedResponse, error)
+	Show(ctx context.Context, req *ollamaApi.ShowRequest) (*ollamaApi.ShowResponse, error)
+	Delete(ctx context.Context, req *ollamaApi.DeleteRequest) error
+	Copy(ctx context.Context, req *ollamaApi.CopyRequest) error
+	Create(ctx context.Context, req *ollamaApi.CreateRequest, fn ollamaApi.CreateProgressFunc) error
+	ListRunning(ctx context.Context) (*ollamaApi.ProcessResponse, error)
+}
+
+var (
+	// Client is the backend used by all commands and listeners, main sets it
+	// with NewBackendFromConfig and tests can swap in a fake
+	Client Backend
+
+	// ErrUnsupported is returned when a backend can't perform an operation
+	ErrUnsupported = errors.New("operation not supported by this backend")
+
+	_ Backend = (*ollamaApi.Client)(nil)
+	_ Backend = (*OpenAIBackend)(nil)
+	_ Backend = (*Router)(nil)
+	_ Backend = (*Pool)(nil)
+)
+
+// NewBackendFromConfig builds the configured backend. LLM_BACKEND selects the
+// default backend, and models listed in OPENAI_MODELS are routed to the
+// OpenAI-compatible server when the default is ollama.
+func NewBackendFromConfig(config *util.Config) (Backend, error) {
+	switch config.LLM_BACKEND {
+	case "ollama":
+		ollamaBackend, err := NewOllamaBackend(config)
+		if err != nil {
+			return nil, err
+		}
+		if config.OPENAI_BASE_URL == "" || config.OPENAI_MODELS == "" {
+			return ollamaBackend, nil
+		}
+
+		openaiBackend := NewOpenAIBackend(config.OPENAI_BASE_URL, config.OPENAI_API_KEY)
+		router := NewRouter(ollamaBackend)
+		for _, model := range util.DeleteEmpty(strings.Split(config.OPENAI_MODELS, ",")) {
+			router.Route(strings.TrimSpace(model), openaiBackend)
+		}
+		return router, nil
+	case "openai":
+		if config.OPENAI_BASE_URL == "" {
+			return nil, errors.New("OPENAI_BASE_URL is not set")
+		}
+		return NewOpenAIBackend(config.OPENAI_BASE_URL, config.OPENAI_API_KEY), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM_BACKEND %s", config.LLM_BACKEND)
+	}
+}
+
+// Hosts returns the state of the Ollama hosts, nil when the backend doesn't
+// spread requests over several hosts
+func Hosts() []HostStatus {
+	backend := Client
+	if router, ok := backend.(*Router); ok {
+		backend = router.fallback
+	}
+	if pool, ok := backend.(*Pool); ok {
+		return pool.Status()
+	}
+	return nil
+}
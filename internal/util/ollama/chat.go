@@ -0,0 +1,95 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util/tools"
+)
+
+// maxToolRounds bounds how often a model can call tools for one answer
+const maxToolRounds = 5
+
+// ChatWithTools runs the chat offering the tools to the model. The tool
+// calls of the model are executed and the chat continues with their results
+// until the model answers, the tokens of every round are counted in the done
+// response. The content of every round is passed to fn, the tool calls and
+// results are returned to record them in the history with the content of
+// their round. Models that can't call tools chat without them.
+func ChatWithTools(ctx context.Context, req *ollamaApi.ChatRequest, env tools.Env, fn ollamaApi.ChatResponseFunc) (toolMessages []ollamaApi.Message, err error) {
+	capable, err := database.ModelSupportsTools(req.Model)
+	if err != nil {
+		slog.Error("Error fetching model tools:", slog.Any("err", err))
+	}
+	if !capable {
+		return nil, Client.Chat(ctx, req, fn)
+	}
+
+	req.Tools = tools.Definitions()
+	var promptTokens, evalTokens int
+	for round := 0; ; round++ {
+		// the last round has to answer with what it has
+		if round == maxToolRounds {
+			req.Tools = nil
+		}
+
+		assistant := ollamaApi.Message{Role: "assistant"}
+		err = Client.Chat(ctx, req, func(cr ollamaApi.ChatResponse) error {
+			assistant.Content += cr.Message.Content
+			assistant.ToolCalls = append(assistant.ToolCalls, cr.Message.ToolCalls...)
+			if !cr.Done {
+				return fn(cr)
+			}
+
+			promptTokens += cr.PromptEvalCount
+			evalTokens += cr.EvalCount
+			if len(assistant.ToolCalls) > 0 {
+				// the answer continues in the next round
+				cr.Done, cr.Message.ToolCalls = false, nil
+				return fn(cr)
+			}
+			cr.PromptEvalCount, cr.EvalCount = promptTokens, evalTokens
+			return fn(cr)
+		})
+		if err != nil || len(assistant.ToolCalls) == 0 {
+			return toolMessages, err
+		}
+
+		toolMessages = append(toolMessages, assistant)
+		for _, call := range assistant.ToolCalls {
+			toolMessages = append(toolMessages, ollamaApi.Message{
+				Role:       "tool",
+				Content:    tools.Call(ctx, env, call),
+				ToolName:   call.Function.Name,
+				ToolCallID: call.ID,
+			})
+		}
+		req.Messages = append(req.Messages, toolMessages[len(toolMessages)-len(assistant.ToolCalls)-1:]...)
+	}
+}
+
+// HistoryMessages converts chat messages to conversation messages to store
+func HistoryMessages(conversationID, model string, messages []ollamaApi.Message) (history []database.Message) {
+	for _, message := range messages {
+		stored := database.Message{
+			ConversationID: conversationID,
+			Role:           message.Role,
+			Content:        message.Content,
+			ModelName:      model,
+			ToolName:       message.ToolName,
+			ToolCallID:     message.ToolCallID,
+		}
+		if len(message.ToolCalls) > 0 {
+			calls, err := json.Marshal(message.ToolCalls)
+			if err != nil {
+				slog.Error("Error encoding tool calls:", slog.Any("err", err))
+			}
+			stored.ToolCalls = string(calls)
+		}
+		history = append(history, stored)
+	}
+	return
+}
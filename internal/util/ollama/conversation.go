@@ -0,0 +1,72 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+var (
+	// ErrNoVision is returned for image attachments sent to a text only model
+	ErrNoVision = errors.New("model does not support images")
+
+	// modelSupportsVision looks up if a model accepts images, tests can swap it
+	modelSupportsVision = database.ModelSupportsVision
+)
+
+// ChatMessages converts stored conversation messages to chat messages
+func ChatMessages(messages []database.Message) (chat []ollamaApi.Message) {
+	for _, message := range messages {
+		chatMessage := ollamaApi.Message{
+			Role:       message.Role,
+			Content:    message.Content,
+			ToolName:   message.ToolName,
+			ToolCallID: message.ToolCallID,
+		}
+		if message.ToolCalls != "" {
+			if err := json.Unmarshal([]byte(message.ToolCalls), &chatMessage.ToolCalls); err != nil {
+				slog.Error("Error decoding tool calls:", slog.Any("err", err))
+			}
+		}
+		chat = append(chat, chatMessage)
+	}
+	return
+}
+
+// MessageImages collects the image attachments and custom emoji of a message
+// for the model. Image attachments for a text only model return ErrNoVision,
+// custom emoji are left as text for those models.
+func MessageImages(model string, message discord.Message) (images []ollamaApi.ImageData, err error) {
+	attachments := util.ImageAttachments(message.Attachments)
+
+	vision, err := modelSupportsVision(model)
+	if err != nil {
+		return nil, err
+	}
+	if !vision {
+		if len(attachments) > 0 {
+			return nil, ErrNoVision
+		}
+		return nil, nil
+	}
+
+	attachmentImages, err := util.FetchAttachmentImages(attachments)
+	if err != nil {
+		return nil, err
+	}
+	// The emoji are a nice to have, the message still goes through without them
+	emojiImages, err := util.FetchEmojiImages(message.Content)
+	if err != nil {
+		slog.Error("Error fetching emoji:", slog.Any("err", err))
+	}
+
+	for _, image := range append(attachmentImages, emojiImages...) {
+		images = append(images, image)
+	}
+	return images, nil
+}
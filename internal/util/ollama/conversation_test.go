@@ -0,0 +1,96 @@
+package ollama
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+func TestMessageImages(t *testing.T) {
+	image := []byte("\x89PNG image")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Write(image)
+		case "/notes.txt":
+			t.Error("the text attachment was downloaded")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(original func(string) (bool, error)) { modelSupportsVision = original }(modelSupportsVision)
+	modelSupportsVision = func(model string) (bool, error) {
+		return model == "vision", nil
+	}
+
+	attachment := func(name, contentType string) discord.Attachment {
+		return discord.Attachment{Filename: name, URL: server.URL + "/" + name, ContentType: &contentType}
+	}
+
+	tests := []struct {
+		name        string
+		model       string
+		attachments []discord.Attachment
+		want        int
+		wantErr     error
+		fails       bool
+	}{
+		{
+			name:        "vision model with an image",
+			model:       "vision",
+			attachments: []discord.Attachment{attachment("image.png", "image/png")},
+			want:        1,
+		},
+		{
+			name:        "text model with an image",
+			model:       "text",
+			attachments: []discord.Attachment{attachment("image.png", "image/png")},
+			wantErr:     ErrNoVision,
+		},
+		{
+			name:        "text model without images",
+			model:       "text",
+			attachments: []discord.Attachment{attachment("notes.txt", "text/plain")},
+		},
+		{
+			name:        "skips other attachments",
+			model:       "vision",
+			attachments: []discord.Attachment{attachment("notes.txt", "text/plain"), attachment("image.png", "image/png")},
+			want:        1,
+		},
+		{
+			name:        "download failure",
+			model:       "vision",
+			attachments: []discord.Attachment{attachment("missing.png", "image/png")},
+			fails:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images, err := MessageImages(tt.model, discord.Message{Content: "look at this", Attachments: tt.attachments})
+			switch {
+			case tt.wantErr != nil || tt.fails:
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			if len(images) != tt.want {
+				t.Fatalf("got %d images, want %d", len(images), tt.want)
+			}
+			for _, got := range images {
+				if string(got) != string(image) {
+					t.Errorf("got image %q, want %q", got, image)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+// embedBatchSize bounds how many inputs are embedded in one request
+const embedBatchSize = 32
+
+// EmbeddingModel returns the model used to embed text for retrieval
+func EmbeddingModel() string {
+	return util.ConfigFile.EMBEDDING_MODEL
+}
+
+// Embed embeds the inputs with the embedding model, the embeddings are
+// returned in the order of the inputs
+func Embed(ctx context.Context, inputs []string) (embeddings [][]float32, err error) {
+	for batch := range slices.Chunk(inputs, embedBatchSize) {
+		res, err := Client.Embed(ctx, &ollamaApi.EmbedRequest{
+			Model: EmbeddingModel(),
+			Input: batch,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(res.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", EmbeddingModel(), len(res.Embeddings), len(batch))
+		}
+		embeddings = append(embeddings, res.Embeddings...)
+	}
+	return
+}
@@ -0,0 +1,121 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/parser"
+	"github.com/ollama/ollama/types/model"
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+// ShowModel returns the model with the details and capabilities the backend reports
+func ShowModel(ctx context.Context, name string) (database.Model, error) {
+	show, err := Client.Show(ctx, &ollamaApi.ShowRequest{Model: name})
+	if err != nil {
+		return database.Model{}, err
+	}
+
+	return database.Model{
+		Name:          name,
+		Family:        show.Details.Family,
+		ParameterSize: show.Details.ParameterSize,
+		Quantization:  show.Details.QuantizationLevel,
+		ContextLength: contextLength(show.ModelInfo),
+		Vision:        slices.Contains(show.Capabilities, model.CapabilityVision),
+		Tools:         slices.Contains(show.Capabilities, model.CapabilityTools),
+		Embedding:     slices.Contains(show.Capabilities, model.CapabilityEmbedding),
+		Thinking:      slices.Contains(show.Capabilities, model.CapabilityThinking),
+	}, nil
+}
+
+// ModelsToOptions lists the models to pick from, a select menu holds at most
+// 25 options
+func ModelsToOptions(models []database.Model) (options []discord.StringSelectMenuOption) {
+	for _, model := range models[:min(len(models), 25)] {
+		options = append(options, discord.StringSelectMenuOption{
+			Label:       model.Name,
+			Value:       model.Name,
+			Description: util.BreakContent(model.Details(), 100)[0],
+		})
+	}
+	return
+}
+
+// contextLength reads the context length from the model info, the key is
+// prefixed with the architecture, like llama.context_length
+func contextLength(info map[string]any) int {
+	for key, value := range info {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if length, ok := value.(float64); ok {
+			return int(length)
+		}
+	}
+	return 0
+}
+
+// CreateRequest parses a Modelfile into the request creating the model name.
+// The Modelfile can only build on existing models, files on the host of the
+// bot can't be used as weights or adapters.
+func CreateRequest(name, modelfile string) (*ollamaApi.CreateRequest, error) {
+	parsed, err := parser.ParseFile(strings.NewReader(modelfile))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, command := range parsed.Commands {
+		switch {
+		case command.Name == "adapter":
+			return nil, fmt.Errorf("ADAPTER is not supported, adapters have to be created on the Ollama host")
+		case command.Name == "model" && strings.IndexAny(command.Args, "./~\\") == 0:
+			return nil, fmt.Errorf("FROM %s is a path, only existing models can be used", command.Args)
+		}
+	}
+
+	req, err := parsed.CreateRequest("")
+	if err != nil {
+		return nil, err
+	}
+	if req.From == "" || len(req.Files) > 0 {
+		return nil, fmt.Errorf("the Modelfile needs a FROM with an existing model")
+	}
+	req.Model = name
+	return req, nil
+}
+
+// defaultContextWindow is the context Ollama runs models with when num_ctx isn't set
+const defaultContextWindow = 4096
+
+// ContextWindow returns how many tokens of context the model runs with, the
+// num_ctx option when it's set and otherwise the Ollama default, bounded by
+// the context length the model supports
+func ContextWindow(name string, options map[string]any) int {
+	window := defaultContextWindow
+	switch numCtx := options["num_ctx"].(type) {
+	case int:
+		window = numCtx
+	case int64:
+		window = int(numCtx)
+	case float64:
+		window = int(numCtx)
+	}
+
+	models, err := database.ListModelDetails()
+	if err != nil {
+		slog.Error("Error fetching model details:", slog.Any("err", err))
+	}
+	for _, model := range models {
+		if model.Name == name && model.ContextLength > 0 {
+			window = min(window, model.ContextLength)
+		}
+	}
+	return window
+}
@@ -1,98 +1,81 @@
 package ollama
 
 import (
-	"bytes"
-	b64 "encoding/base64"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/envconfig"
 	"github.com/stollenaar/ollamabot/internal/util"
 )
 
-func List() (ListResponse, error) {
-	bodyData, err := do([]byte{}, "tags")
-
-	if err != nil {
-		return ListResponse{}, err
-	}
-
-	var r ListResponse
-	json.Unmarshal([]byte(bodyData), &r)
-	return r, nil
+// basicAuthTransport adds the configured ollama basic auth credentials to every request
+type basicAuthTransport struct {
+	next http.RoundTripper
 }
 
-func Pull(pull PullRequest) error {
-	data, err := json.Marshal(pull)
+func (t basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	username, err := util.GetOllamaUsername()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = do(data, "pull")
-	return err
-}
-
-func Generate(prompt GenerateRequest) (GenerateResponse, error) {
 
-	data, err := json.Marshal(prompt)
+	password, err := util.GetOllamaPassword()
 	if err != nil {
-		return GenerateResponse{}, err
+		return nil, err
 	}
 
-	bodyData, err := do(data, "generate")
-	if err != nil {
-		return GenerateResponse{}, err
-	}
-	var r GenerateResponse
-	json.Unmarshal([]byte(bodyData), &r)
-	return r, nil
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(username, password)
+	return t.next.RoundTrip(req)
 }
 
-func do(data []byte, endpoint string) (string, error) {
-	// os.WriteFile("req.json", data, 0644)
-
-	req, err := http.NewRequest("POST", fmt.Sprintf("http://%s/api/%s", util.ConfigFile.OLLAMA_URL, endpoint), bytes.NewBuffer(data))
-
-	if err != nil {
-		fmt.Println(err)
-		return "", err
-	}
-	req.Header.Add("Content-Type", "application/json")
-
-	switch util.ConfigFile.OLLAMA_AUTH_TYPE {
-	case "basic":
-		username, err := util.GetOllamaUsername()
-		if err != nil {
-			fmt.Println(err)
-			return "", err
+// NewOllamaBackend creates an ollama client for OLLAMA_URL, falling back to
+// OLLAMA_HOST. When OLLAMA_HOSTS lists several hosts a Pool over them is
+// returned instead.
+func NewOllamaBackend(config *util.Config) (Backend, error) {
+	httpClient := http.DefaultClient
+	if config.OLLAMA_AUTH_TYPE == "basic" {
+		httpClient = &http.Client{
+			Transport: basicAuthTransport{next: http.DefaultTransport},
 		}
+	}
 
-		password, err := util.GetOllamaPassword()
-		if err != nil {
-			fmt.Println(err)
-			return "", err
+	hosts := util.DeleteEmpty(strings.Split(config.OLLAMA_HOSTS, ","))
+	if len(hosts) == 0 {
+		base := envconfig.Host()
+		if config.OLLAMA_URL != "" {
+			u, err := parseHost(config.OLLAMA_URL)
+			if err != nil {
+				return nil, err
+			}
+			base = u
 		}
-
-		token := b64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password)))
-		req.Header.Add("Authorization", fmt.Sprintf("Basic %s", token))
+		return ollamaApi.NewClient(base, httpClient), nil
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	interval, err := time.ParseDuration(config.OLLAMA_POLL_INTERVAL)
 	if err != nil {
-		fmt.Println(err)
-		return "", err
+		return nil, fmt.Errorf("invalid OLLAMA_POLL_INTERVAL: %w", err)
 	}
 
-	var bodyData string
-	if resp != nil {
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(resp.Body)
-		bodyData = buf.String()
+	clients := make(map[string]*ollamaApi.Client)
+	for _, host := range hosts {
+		u, err := parseHost(strings.TrimSpace(host))
+		if err != nil {
+			return nil, err
+		}
+		clients[u.Host] = ollamaApi.NewClient(u, httpClient)
 	}
-	if resp.StatusCode != 200 {
-		fmt.Println(bodyData)
-		return "", errors.New(bodyData)
+	return NewPool(clients, interval), nil
+}
+
+func parseHost(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
 	}
-	return bodyData, nil
+	return url.Parse(raw)
 }
@@ -0,0 +1,464 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	b64 "encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/types/model"
+)
+
+// OpenAIBackend talks to any server exposing the OpenAI HTTP API, like a
+// llama.cpp server or vLLM. BaseURL includes the version prefix, e.g.
+// http://localhost:8080/v1
+type OpenAIBackend struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIChatRequest struct {
+	Model         string              `json:"model"`
+	Messages      []openAIMessage     `json:"messages"`
+	Stream        bool                `json:"stream"`
+	StreamOptions *openAIStreamOption `json:"stream_options,omitempty"`
+	Tools         []ollamaApi.Tool    `json:"tools,omitempty"`
+	Temperature   any                 `json:"temperature,omitempty"`
+	TopP          any                 `json:"top_p,omitempty"`
+	Seed          any                 `json:"seed,omitempty"`
+	Stop          any                 `json:"stop,omitempty"`
+	MaxTokens     any                 `json:"max_tokens,omitempty"`
+}
+
+type openAIStreamOption struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Created int64  `json:"created"`
+	Choices []struct {
+		Message      openAIResponseMessage `json:"message"`
+		Delta        openAIResponseMessage `json:"delta"`
+		FinishReason *string               `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAIResponseMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []openAIToolCall `json:"tool_calls"`
+}
+
+type openAIError struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewOpenAIBackend creates a backend for the OpenAI-compatible server at baseURL
+func NewOpenAIBackend(baseURL, apiKey string) *OpenAIBackend {
+	return &OpenAIBackend{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Chat sends the conversation to /chat/completions, streaming unless req.Stream is false
+func (o *OpenAIBackend) Chat(ctx context.Context, req *ollamaApi.ChatRequest, fn ollamaApi.ChatResponseFunc) error {
+	stream := req.Stream == nil || *req.Stream
+
+	body := openAIChatRequest{
+		Model:       req.Model,
+		Stream:      stream,
+		Tools:       req.Tools,
+		Temperature: req.Options["temperature"],
+		TopP:        req.Options["top_p"],
+		Seed:        req.Options["seed"],
+		Stop:        req.Options["stop"],
+		MaxTokens:   req.Options["num_predict"],
+	}
+	if stream {
+		body.StreamOptions = &openAIStreamOption{IncludeUsage: true}
+	}
+	for _, message := range req.Messages {
+		body.Messages = append(body.Messages, toOpenAIMessage(message))
+	}
+
+	resp, err := o.do(ctx, http.MethodPost, "chat/completions", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !stream {
+		var r openAIChatResponse
+		if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+			return err
+		}
+		chat := ollamaApi.ChatResponse{
+			Model:     r.Model,
+			CreatedAt: time.Unix(r.Created, 0),
+			Message:   ollamaApi.Message{Role: "assistant"},
+			Done:      true,
+		}
+		if len(r.Choices) > 0 {
+			chat.Message.Content = r.Choices[0].Message.Content
+			chat.Message.ToolCalls = fromOpenAIToolCalls(r.Choices[0].Message.ToolCalls)
+			if r.Choices[0].FinishReason != nil {
+				chat.DoneReason = *r.Choices[0].FinishReason
+			}
+		}
+		if r.Usage != nil {
+			chat.PromptEvalCount = r.Usage.PromptTokens
+			chat.EvalCount = r.Usage.CompletionTokens
+		}
+		return fn(chat)
+	}
+
+	var doneReason string
+	var usage openAIUsage
+	var toolCalls []openAIToolCall
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != nil {
+			doneReason = *choice.FinishReason
+		}
+		toolCalls = mergeOpenAIToolCalls(toolCalls, choice.Delta.ToolCalls)
+		if choice.Delta.Content == "" {
+			continue
+		}
+		err := fn(ollamaApi.ChatResponse{
+			Model:     chunk.Model,
+			CreatedAt: time.Unix(chunk.Created, 0),
+			Message: ollamaApi.Message{
+				Role:    "assistant",
+				Content: choice.Delta.Content,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	final := ollamaApi.ChatResponse{
+		Model:      req.Model,
+		CreatedAt:  time.Now(),
+		Message:    ollamaApi.Message{Role: "assistant", ToolCalls: fromOpenAIToolCalls(toolCalls)},
+		Done:       true,
+		DoneReason: doneReason,
+	}
+	final.PromptEvalCount = usage.PromptTokens
+	final.EvalCount = usage.CompletionTokens
+	return fn(final)
+}
+
+// Generate maps a single prompt onto a chat completion. The deprecated
+// Context tokens have no OpenAI equivalent and are ignored.
+func (o *OpenAIBackend) Generate(ctx context.Context, req *ollamaApi.GenerateRequest, fn ollamaApi.GenerateResponseFunc) error {
+	var messages []ollamaApi.Message
+	if req.System != "" {
+		messages = append(messages, ollamaApi.Message{Role: "system", Content: req.System})
+	}
+	messages = append(messages, ollamaApi.Message{Role: "user", Content: req.Prompt, Images: req.Images})
+
+	return o.Chat(ctx, &ollamaApi.ChatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Stream:   req.Stream,
+		Options:  req.Options,
+	}, func(cr ollamaApi.ChatResponse) error {
+		return fn(ollamaApi.GenerateResponse{
+			Model:      cr.Model,
+			CreatedAt:  cr.CreatedAt,
+			Response:   cr.Message.Content,
+			Done:       cr.Done,
+			DoneReason: cr.DoneReason,
+			Metrics:    cr.Metrics,
+		})
+	})
+}
+
+// List returns the models served at /models
+func (o *OpenAIBackend) List(ctx context.Context) (*ollamaApi.ListResponse, error) {
+	resp, err := o.do(ctx, http.MethodGet, "models", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Data []struct {
+			ID      string `json:"id"`
+			Created int64  `json:"created"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	list := &ollamaApi.ListResponse{}
+	for _, m := range r.Data {
+		list.Models = append(list.Models, ollamaApi.ListModelResponse{
+			Name:       m.ID,
+			Model:      m.ID,
+			ModifiedAt: time.Unix(m.Created, 0),
+		})
+	}
+	return list, nil
+}
+
+// Pull isn't part of the OpenAI API, models are managed on the server itself
+func (o *OpenAIBackend) Pull(ctx context.Context, req *ollamaApi.PullRequest, fn ollamaApi.PullProgressFunc) error {
+	return ErrUnsupported
+}
+
+// Delete isn't part of the OpenAI API, models are managed on the server itself
+func (o *OpenAIBackend) Delete(ctx context.Context, req *ollamaApi.DeleteRequest) error {
+	return ErrUnsupported
+}
+
+// Copy isn't part of the OpenAI API, models are managed on the server itself
+func (o *OpenAIBackend) Copy(ctx context.Context, req *ollamaApi.CopyRequest) error {
+	return ErrUnsupported
+}
+
+// Create isn't part of the OpenAI API, models are managed on the server itself
+func (o *OpenAIBackend) Create(ctx context.Context, req *ollamaApi.CreateRequest, fn ollamaApi.CreateProgressFunc) error {
+	return ErrUnsupported
+}
+
+// ListRunning isn't part of the OpenAI API, the server loads models itself
+func (o *OpenAIBackend) ListRunning(ctx context.Context) (*ollamaApi.ProcessResponse, error) {
+	return nil, ErrUnsupported
+}
+
+// Embed sends the input to /embeddings
+func (o *OpenAIBackend) Embed(ctx context.Context, req *ollamaApi.EmbedRequest) (*ollamaApi.EmbedResponse, error) {
+	resp, err := o.do(ctx, http.MethodPost, "embeddings", map[string]any{
+		"model": req.Model,
+		"input": req.Input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Model string `json:"model"`
+		Data  []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	embed := &ollamaApi.EmbedResponse{
+		Model:           r.Model,
+		PromptEvalCount: r.Usage.PromptTokens,
+	}
+	for _, d := range r.Data {
+		embed.Embeddings = append(embed.Embeddings, d.Embedding)
+	}
+	return embed, nil
+}
+
+// Show only confirms the model is served, the OpenAI API exposes no model metadata
+func (o *OpenAIBackend) Show(ctx context.Context, req *ollamaApi.ShowRequest) (*ollamaApi.ShowResponse, error) {
+	list, err := o.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list.Models {
+		if m.Model == req.Model {
+			return &ollamaApi.ShowResponse{
+				Capabilities: []model.Capability{model.CapabilityCompletion},
+				ModifiedAt:   m.ModifiedAt,
+			}, nil
+		}
+	}
+	return nil, ollamaApi.StatusError{
+		StatusCode:   http.StatusNotFound,
+		Status:       http.StatusText(http.StatusNotFound),
+		ErrorMessage: fmt.Sprintf("model '%s' not found", req.Model),
+	}
+}
+
+func (o *OpenAIBackend) do(ctx context.Context, method, endpoint string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s", o.BaseURL, endpoint), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+
+		statusErr := ollamaApi.StatusError{
+			StatusCode:   resp.StatusCode,
+			Status:       resp.Status,
+			ErrorMessage: string(data),
+		}
+		var apiErr openAIError
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Message != "" {
+			statusErr.ErrorMessage = apiErr.Error.Message
+		}
+		return nil, statusErr
+	}
+	return resp, nil
+}
+
+func toOpenAIMessage(message ollamaApi.Message) openAIMessage {
+	m := openAIMessage{
+		Role:    message.Role,
+		Content: message.Content,
+	}
+	if message.Role == "tool" {
+		m.ToolCallID = message.ToolCallID
+	}
+	for i, call := range message.ToolCalls {
+		args, _ := json.Marshal(call.Function.Arguments)
+		tc := openAIToolCall{Index: i, ID: call.ID, Type: "function"}
+		tc.Function.Name = call.Function.Name
+		tc.Function.Arguments = string(args)
+		m.ToolCalls = append(m.ToolCalls, tc)
+	}
+
+	if len(message.Images) == 0 {
+		return m
+	}
+
+	parts := []openAIContentPart{{Type: "text", Text: message.Content}}
+	for _, image := range message.Images {
+		parts = append(parts, openAIContentPart{
+			Type: "image_url",
+			ImageURL: &openAIImageURL{
+				URL: fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(image), b64.StdEncoding.EncodeToString(image)),
+			},
+		})
+	}
+	m.Content = parts
+	return m
+}
+
+// mergeOpenAIToolCalls accumulates the streamed tool call fragments by index
+func mergeOpenAIToolCalls(calls, deltas []openAIToolCall) []openAIToolCall {
+	for _, delta := range deltas {
+		for len(calls) <= delta.Index {
+			calls = append(calls, openAIToolCall{Type: "function"})
+		}
+		call := &calls[delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		call.Function.Name += delta.Function.Name
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
+
+func fromOpenAIToolCalls(calls []openAIToolCall) (toolCalls []ollamaApi.ToolCall) {
+	for i, call := range calls {
+		args := ollamaApi.ToolCallFunctionArguments{}
+		if call.Function.Arguments != "" {
+			_ = json.Unmarshal([]byte(call.Function.Arguments), &args)
+		}
+		toolCalls = append(toolCalls, ollamaApi.ToolCall{
+			ID: call.ID,
+			Function: ollamaApi.ToolCallFunction{
+				Index:     i,
+				Name:      call.Function.Name,
+				Arguments: args,
+			},
+		})
+	}
+	return
+}
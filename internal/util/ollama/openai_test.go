@@ -0,0 +1,156 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// fakeBackend answers every chat with a fixed reply, the other operations
+// panic on the nil Backend
+type fakeBackend struct {
+	Backend
+	reply string
+}
+
+func (f fakeBackend) Chat(ctx context.Context, req *ollamaApi.ChatRequest, fn ollamaApi.ChatResponseFunc) error {
+	return fn(ollamaApi.ChatResponse{
+		Model:   req.Model,
+		Message: ollamaApi.Message{Role: "assistant", Content: f.reply},
+		Done:    true,
+	})
+}
+
+// openAIServer serves /v1/chat/completions with the handler, recording the
+// decoded requests
+func openAIServer(t *testing.T, handler func(w http.ResponseWriter, req openAIChatRequest)) (*httptest.Server, *[]openAIChatRequest) {
+	t.Helper()
+	var requests []openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		requests = append(requests, req)
+		handler(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRouterSendsRoutedModelsToOpenAIBackend(t *testing.T) {
+	server, requests := openAIServer(t, func(w http.ResponseWriter, req openAIChatRequest) {
+		fmt.Fprint(w, `{"model":"remote","choices":[{"message":{"role":"assistant","content":"from openai"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2}}`)
+	})
+
+	router := NewRouter(fakeBackend{reply: "from fallback"})
+	router.Route("remote", NewOpenAIBackend(server.URL+"/v1/", ""))
+
+	tests := []struct {
+		model  string
+		want   string
+		tokens int
+	}{
+		{model: "remote", want: "from openai", tokens: 5},
+		{model: "local", want: "from fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			var got ollamaApi.ChatResponse
+			err := router.Chat(context.Background(), &ollamaApi.ChatRequest{
+				Model:    tt.model,
+				Messages: []ollamaApi.Message{{Role: "user", Content: "hi"}},
+				Stream:   new(bool),
+			}, func(cr ollamaApi.ChatResponse) error {
+				got = cr
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Message.Content != tt.want || !got.Done {
+				t.Errorf("got %q done %v, want %q done", got.Message.Content, got.Done, tt.want)
+			}
+			if tokens := got.PromptEvalCount + got.EvalCount; tokens != tt.tokens {
+				t.Errorf("got %d tokens, want %d", tokens, tt.tokens)
+			}
+		})
+	}
+	if len(*requests) != 1 || (*requests)[0].Model != "remote" {
+		t.Errorf("OpenAI server got %+v, want one request for remote", *requests)
+	}
+}
+
+func TestOpenAIBackendRoundTripsToolCallIDs(t *testing.T) {
+	server, requests := openAIServer(t, func(w http.ResponseWriter, req openAIChatRequest) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"calculator","arguments":""}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"expression\":\"1+1\"}"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_b","type":"function","function":{"name":"calculator","arguments":"{\"expression\":\"2*3\"}"}}]}}]}`,
+			`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	})
+	backend := NewOpenAIBackend(server.URL+"/v1", "")
+
+	var calls []ollamaApi.ToolCall
+	err := backend.Chat(context.Background(), &ollamaApi.ChatRequest{
+		Model:    "remote",
+		Messages: []ollamaApi.Message{{Role: "user", Content: "what are 1+1 and 2*3"}},
+	}, func(cr ollamaApi.ChatResponse) error {
+		calls = append(calls, cr.Message.ToolCalls...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantIDs := []string{"call_a", "call_b"}
+	if len(calls) != len(wantIDs) {
+		t.Fatalf("got %d tool calls, want %d", len(calls), len(wantIDs))
+	}
+	for i, call := range calls {
+		if call.ID != wantIDs[i] || call.Function.Name != "calculator" {
+			t.Errorf("call %d is %s %s, want %s calculator", i, call.ID, call.Function.Name, wantIDs[i])
+		}
+	}
+	if calls[0].Function.Arguments["expression"] != "1+1" {
+		t.Errorf("call 0 has arguments %v, want the merged fragments", calls[0].Function.Arguments)
+	}
+
+	// the results go back answering the calls by their ids
+	messages := []ollamaApi.Message{
+		{Role: "user", Content: "what are 1+1 and 2*3"},
+		{Role: "assistant", ToolCalls: calls},
+		{Role: "tool", Content: "2", ToolName: "calculator", ToolCallID: "call_a"},
+		{Role: "tool", Content: "6", ToolName: "calculator", ToolCallID: "call_b"},
+	}
+	err = backend.Chat(context.Background(), &ollamaApi.ChatRequest{Model: "remote", Messages: messages}, func(ollamaApi.ChatResponse) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := (*requests)[1].Messages
+	for i, call := range sent[1].ToolCalls {
+		if call.ID != wantIDs[i] {
+			t.Errorf("sent tool call %d with id %q, want %q", i, call.ID, wantIDs[i])
+		}
+	}
+	for i, message := range sent[2:] {
+		if message.ToolCallID != wantIDs[i] {
+			t.Errorf("sent tool result %d for %q, want %q", i, message.ToolCallID, wantIDs[i])
+		}
+	}
+}
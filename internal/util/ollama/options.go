@@ -0,0 +1,116 @@
+package ollama
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+)
+
+// ParseOptions parses generation options written as key=value pairs
+// separated by spaces or newlines, values with spaces can be quoted like
+// stop="User:". The names and types are validated against the Ollama
+// options, a repeated stop adds another stop sequence.
+func ParseOptions(text string) (map[string]any, error) {
+	fields, err := SplitFields(text)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string][]string)
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid option %s, options are written as name=value", field)
+		}
+		params[key] = append(params[key], value)
+	}
+	return ollamaApi.FormatParams(params)
+}
+
+// SplitFields splits on whitespace outside of double quotes, the quotes are
+// removed and inside them \n, \t and backslash escapes are read like Go strings
+func SplitFields(text string) (fields []string, err error) {
+	var field strings.Builder
+	inField, quoted, escaped := false, false, false
+	for _, r := range text {
+		switch {
+		case escaped:
+			switch r {
+			case 'n':
+				field.WriteRune('\n')
+			case 't':
+				field.WriteRune('\t')
+			default:
+				field.WriteRune(r)
+			}
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && unicode.IsSpace(r):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("invalid options, a quote is not closed")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return
+}
+
+// FormatOptions writes the options in the format ParseOptions reads
+func FormatOptions(options map[string]any) string {
+	var pairs []string
+	for _, key := range slices.Sorted(maps.Keys(options)) {
+		switch value := options[key].(type) {
+		case []any:
+			for _, v := range value {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", key, strconv.Quote(fmt.Sprint(v))))
+			}
+		case []string:
+			for _, v := range value {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", key, strconv.Quote(v)))
+			}
+		default:
+			pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Options returns the default options of the model with the overrides
+// applied in order, later overrides win
+func Options(model string, overrides ...map[string]any) map[string]any {
+	options, err := database.GetModelOptions(model)
+	if err != nil {
+		slog.Error("Error fetching model options:", slog.Any("err", err))
+	}
+
+	merged := make(map[string]any)
+	maps.Copy(merged, options)
+	for _, override := range overrides {
+		maps.Copy(merged, override)
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
@@ -0,0 +1,353 @@
+package ollama
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// pollTimeout bounds a single health check of a host
+const pollTimeout = 10 * time.Second
+
+// Pool spreads the requests over several Ollama hosts. The hosts are polled
+// for their health and models, each request goes to the best host for its
+// model and fails over to the next host when a host can't serve it.
+type Pool struct {
+	hosts []*host
+}
+
+type host struct {
+	name   string
+	client *ollamaApi.Client
+
+	mu      sync.RWMutex
+	healthy bool
+	err     error
+	checked time.Time
+	models  map[string]bool
+	loaded  map[string]bool
+}
+
+// HostStatus is the last polled state of a host
+type HostStatus struct {
+	Name    string
+	Healthy bool
+	Err     error
+	Checked time.Time
+	Models  []string
+	Loaded  []string
+}
+
+// NewPool creates a pool of the named clients, polling them every interval.
+// Hosts count as healthy until the first poll says otherwise.
+func NewPool(clients map[string]*ollamaApi.Client, interval time.Duration) *Pool {
+	pool := &Pool{}
+	for name, client := range clients {
+		pool.hosts = append(pool.hosts, &host{
+			name:    name,
+			client:  client,
+			healthy: true,
+			models:  make(map[string]bool),
+			loaded:  make(map[string]bool),
+		})
+	}
+	slices.SortFunc(pool.hosts, func(a, b *host) int { return strings.Compare(a.name, b.name) })
+
+	go func() {
+		for {
+			pool.poll()
+			time.Sleep(interval)
+		}
+	}()
+	return pool
+}
+
+// poll refreshes the health and models of every host
+func (p *Pool) poll() {
+	var wg sync.WaitGroup
+	for _, h := range p.hosts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.refresh()
+		}()
+	}
+	wg.Wait()
+}
+
+func (h *host) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	defer cancel()
+
+	models := make(map[string]bool)
+	loaded := make(map[string]bool)
+	list, err := h.client.List(ctx)
+	if err == nil {
+		for _, model := range list.Models {
+			models[model.Model] = true
+		}
+		var running *ollamaApi.ProcessResponse
+		running, err = h.client.ListRunning(ctx)
+		if err == nil {
+			for _, model := range running.Models {
+				loaded[model.Model] = true
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil && h.healthy {
+		slog.Error("Ollama host is unhealthy:", slog.String("host", h.name), slog.Any("err", err))
+	}
+	h.healthy, h.err, h.checked = err == nil, err, time.Now()
+	if err == nil {
+		h.models, h.loaded = models, loaded
+	}
+}
+
+func (h *host) markUnhealthy(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	slog.Error("Ollama host failed, failing over:", slog.String("host", h.name), slog.Any("err", err))
+	h.healthy, h.err = false, err
+}
+
+// rank scores how well the host can serve the model, healthy hosts first,
+// then hosts that have the model and then hosts that already loaded it
+func (h *host) rank(model string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	score := 0
+	if h.healthy {
+		score += 4
+	}
+	if h.models[model] {
+		score += 2
+	}
+	if h.loaded[model] {
+		score++
+	}
+	return score
+}
+
+func (h *host) has(model string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.models[model]
+}
+
+// candidates returns the hosts in the order they are tried for the model
+func (p *Pool) candidates(model string) []*host {
+	model = normalizeModel(model)
+	hosts := slices.Clone(p.hosts)
+	slices.SortStableFunc(hosts, func(a, b *host) int {
+		return cmp.Compare(b.rank(model), a.rank(model))
+	})
+	return hosts
+}
+
+// do runs the request on the candidates for the model until one serves it.
+// A request that already started streaming isn't retried on another host.
+func (p *Pool) do(ctx context.Context, model string, fn func(client *ollamaApi.Client, started *bool) error) (err error) {
+	for _, h := range p.candidates(model) {
+		started := false
+		err = fn(h.client, &started)
+		if err == nil || started || !shouldFailover(ctx, err) {
+			return err
+		}
+
+		var statusErr ollamaApi.StatusError
+		if !errors.As(err, &statusErr) {
+			h.markUnhealthy(err)
+		}
+	}
+	return err
+}
+
+// shouldFailover reports if another host could serve the failed request,
+// which is the case for connection errors, missing models and server errors
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var statusErr ollamaApi.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
+
+// normalizeModel adds the latest tag Ollama lists untagged models with
+func normalizeModel(model string) string {
+	if !strings.Contains(model, ":") {
+		return model + ":latest"
+	}
+	return model
+}
+
+func (p *Pool) Generate(ctx context.Context, req *ollamaApi.GenerateRequest, fn ollamaApi.GenerateResponseFunc) error {
+	return p.do(ctx, req.Model, func(client *ollamaApi.Client, started *bool) error {
+		return client.Generate(ctx, req, func(gr ollamaApi.GenerateResponse) error {
+			*started = true
+			return fn(gr)
+		})
+	})
+}
+
+func (p *Pool) Chat(ctx context.Context, req *ollamaApi.ChatRequest, fn ollamaApi.ChatResponseFunc) error {
+	return p.do(ctx, req.Model, func(client *ollamaApi.Client, started *bool) error {
+		return client.Chat(ctx, req, func(cr ollamaApi.ChatResponse) error {
+			*started = true
+			return fn(cr)
+		})
+	})
+}
+
+func (p *Pool) Embed(ctx context.Context, req *ollamaApi.EmbedRequest) (resp *ollamaApi.EmbedResponse, err error) {
+	err = p.do(ctx, req.Model, func(client *ollamaApi.Client, started *bool) error {
+		resp, err = client.Embed(ctx, req)
+		return err
+	})
+	return
+}
+
+func (p *Pool) Show(ctx context.Context, req *ollamaApi.ShowRequest) (resp *ollamaApi.ShowResponse, err error) {
+	err = p.do(ctx, req.Model, func(client *ollamaApi.Client, started *bool) error {
+		resp, err = client.Show(ctx, req)
+		return err
+	})
+	return
+}
+
+// Pull downloads the model on the best host, a host that already has the
+// model updates it
+func (p *Pool) Pull(ctx context.Context, req *ollamaApi.PullRequest, fn ollamaApi.PullProgressFunc) error {
+	return p.do(ctx, req.Model, func(client *ollamaApi.Client, started *bool) error {
+		defer p.poll()
+		return client.Pull(ctx, req, func(pr ollamaApi.ProgressResponse) error {
+			*started = true
+			return fn(pr)
+		})
+	})
+}
+
+// Create creates the model on the best host for the model it builds on
+func (p *Pool) Create(ctx context.Context, req *ollamaApi.CreateRequest, fn ollamaApi.CreateProgressFunc) error {
+	return p.do(ctx, req.From, func(client *ollamaApi.Client, started *bool) error {
+		defer p.poll()
+		return client.Create(ctx, req, func(pr ollamaApi.ProgressResponse) error {
+			*started = true
+			return fn(pr)
+		})
+	})
+}
+
+// Delete removes the model from every host that has it
+func (p *Pool) Delete(ctx context.Context, req *ollamaApi.DeleteRequest) error {
+	return p.onHosts(req.Model, func(client *ollamaApi.Client) error {
+		return client.Delete(ctx, req)
+	})
+}
+
+// Copy copies the model on every host that has it
+func (p *Pool) Copy(ctx context.Context, req *ollamaApi.CopyRequest) error {
+	return p.onHosts(req.Source, func(client *ollamaApi.Client) error {
+		return client.Copy(ctx, req)
+	})
+}
+
+// onHosts runs fn on every host with the model, or on the best host when no
+// host is known to have it
+func (p *Pool) onHosts(model string, fn func(client *ollamaApi.Client) error) error {
+	defer p.poll()
+
+	var errs []error
+	found := false
+	for _, h := range p.hosts {
+		if h.has(normalizeModel(model)) {
+			found = true
+			errs = append(errs, fn(h.client))
+		}
+	}
+	if !found {
+		return fn(p.candidates(model)[0].client)
+	}
+	return errors.Join(errs...)
+}
+
+// List merges the models of the reachable hosts
+func (p *Pool) List(ctx context.Context) (*ollamaApi.ListResponse, error) {
+	merged := &ollamaApi.ListResponse{}
+	seen := make(map[string]bool)
+	var errs []error
+	for _, h := range p.hosts {
+		resp, err := h.client.List(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, model := range resp.Models {
+			if !seen[model.Model] {
+				seen[model.Model] = true
+				merged.Models = append(merged.Models, model)
+			}
+		}
+	}
+	if len(errs) == len(p.hosts) {
+		return nil, errors.Join(errs...)
+	}
+	return merged, nil
+}
+
+// ListRunning merges the loaded models of the reachable hosts
+func (p *Pool) ListRunning(ctx context.Context) (*ollamaApi.ProcessResponse, error) {
+	merged := &ollamaApi.ProcessResponse{}
+	var errs []error
+	for _, h := range p.hosts {
+		resp, err := h.client.ListRunning(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		merged.Models = append(merged.Models, resp.Models...)
+	}
+	if len(errs) == len(p.hosts) {
+		return nil, errors.Join(errs...)
+	}
+	return merged, nil
+}
+
+// Status returns the last polled state of the hosts
+func (p *Pool) Status() (statuses []HostStatus) {
+	for _, h := range p.hosts {
+		h.mu.RLock()
+		status := HostStatus{
+			Name:    h.name,
+			Healthy: h.healthy,
+			Err:     h.err,
+			Checked: h.checked,
+		}
+		for model := range h.models {
+			status.Models = append(status.Models, model)
+		}
+		for model := range h.loaded {
+			status.Loaded = append(status.Loaded, model)
+		}
+		h.mu.RUnlock()
+
+		slices.Sort(status.Models)
+		slices.Sort(status.Loaded)
+		statuses = append(statuses, status)
+	}
+	return
+}
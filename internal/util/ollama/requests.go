@@ -0,0 +1,134 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+// request is an in-flight generation that can be stopped
+type request struct {
+	user    string
+	channel string
+	cancel  context.CancelFunc
+}
+
+var (
+	// shutdown is cancelled when the bot stops, stopping every request
+	shutdown, stopAll = context.WithCancel(context.Background())
+
+	requests   = make(map[string]*request)
+	requestsMu sync.Mutex
+	requestsWg sync.WaitGroup
+
+	generationTimeout time.Duration
+)
+
+func init() {
+	var err error
+	generationTimeout, err = time.ParseDuration(util.ConfigFile.GENERATION_TIMEOUT)
+	if err != nil {
+		log.Fatal("Error parsing GENERATION_TIMEOUT: ", err)
+	}
+}
+
+// NewRequest registers a request under id, made by user in channel. The
+// returned context is cancelled by Stop, StopChannel and Shutdown, the done
+// function must be called when the request ends.
+func NewRequest(parent context.Context, id, channel, user string) (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(shutdown, cancel)
+
+	requestsMu.Lock()
+	requests[id] = &request{user: user, channel: channel, cancel: cancel}
+	requestsWg.Add(1)
+	requestsMu.Unlock()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			requestsMu.Lock()
+			delete(requests, id)
+			requestsMu.Unlock()
+
+			stop()
+			cancel()
+			requestsWg.Done()
+		})
+	}
+}
+
+// WithGenerationTimeout bounds a generation by GENERATION_TIMEOUT, it is
+// applied after waiting in the queue so the wait doesn't count
+func WithGenerationTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if generationTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, generationTimeout)
+}
+
+// Stop stops the request with the id when user made it or is the admin,
+// reports false when no such request is running
+func Stop(id, user string) (bool, error) {
+	requestsMu.Lock()
+	defer requestsMu.Unlock()
+
+	r, ok := requests[id]
+	if !ok {
+		return false, nil
+	}
+	if r.user != user && user != util.ConfigFile.ADMIN_USER_ID {
+		return false, errors.New("only the person who asked can stop this answer")
+	}
+	r.cancel()
+	return true, nil
+}
+
+// StopChannel stops the requests user made in the channel, or every request
+// in it when user is the admin, returning how many there were
+func StopChannel(channel, user string) (stopped int) {
+	requestsMu.Lock()
+	defer requestsMu.Unlock()
+
+	for _, r := range requests {
+		if r.channel == channel && (r.user == user || user == util.ConfigFile.ADMIN_USER_ID) {
+			r.cancel()
+			stopped++
+		}
+	}
+	return
+}
+
+// Shutdown stops every request and waits until they ended or ctx is done
+func Shutdown(ctx context.Context) {
+	stopAll()
+
+	ended := make(chan struct{})
+	go func() {
+		requestsWg.Wait()
+		close(ended)
+	}()
+	select {
+	case <-ended:
+	case <-ctx.Done():
+	}
+}
+
+// StopReason describes why a generation ended early, empty when err isn't
+// caused by a stop, a timeout or the shutdown
+func StopReason(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("Stopped after %s, the answer took too long", generationTimeout)
+	case errors.Is(err, context.Canceled) && shutdown.Err() != nil:
+		return "Stopped because the bot is restarting"
+	case errors.Is(err, context.Canceled):
+		return "Stopped"
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package ollama
+
+import (
+	"context"
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// Router dispatches each request to the backend registered for its model,
+// falling back to the default backend
+type Router struct {
+	fallback Backend
+	models   map[string]Backend
+}
+
+// NewRouter creates a router sending unrouted models to fallback
+func NewRouter(fallback Backend) *Router {
+	return &Router{
+		fallback: fallback,
+		models:   make(map[string]Backend),
+	}
+}
+
+// Route sends all requests for model to backend
+func (r *Router) Route(model string, backend Backend) {
+	r.models[model] = backend
+}
+
+// For returns the backend serving model
+func (r *Router) For(model string) Backend {
+	if backend, ok := r.models[model]; ok {
+		return backend
+	}
+	return r.fallback
+}
+
+func (r *Router) Generate(ctx context.Context, req *ollamaApi.GenerateRequest, fn ollamaApi.GenerateResponseFunc) error {
+	return r.For(req.Model).Generate(ctx, req, fn)
+}
+
+func (r *Router) Chat(ctx context.Context, req *ollamaApi.ChatRequest, fn ollamaApi.ChatResponseFunc) error {
+	return r.For(req.Model).Chat(ctx, req, fn)
+}
+
+func (r *Router) Pull(ctx context.Context, req *ollamaApi.PullRequest, fn ollamaApi.PullProgressFunc) error {
+	return r.For(req.Model).Pull(ctx, req, fn)
+}
+
+func (r *Router) Embed(ctx context.Context, req *ollamaApi.EmbedRequest) (*ollamaApi.EmbedResponse, error) {
+	return r.For(req.Model).Embed(ctx, req)
+}
+
+func (r *Router) Show(ctx context.Context, req *ollamaApi.ShowRequest) (*ollamaApi.ShowResponse, error) {
+	return r.For(req.Model).Show(ctx, req)
+}
+
+func (r *Router) Delete(ctx context.Context, req *ollamaApi.DeleteRequest) error {
+	return r.For(req.Model).Delete(ctx, req)
+}
+
+func (r *Router) Copy(ctx context.Context, req *ollamaApi.CopyRequest) error {
+	return r.For(req.Source).Copy(ctx, req)
+}
+
+func (r *Router) Create(ctx context.Context, req *ollamaApi.CreateRequest, fn ollamaApi.CreateProgressFunc) error {
+	return r.For(req.Model).Create(ctx, req, fn)
+}
+
+// ListRunning returns the models loaded by the default backend, the routed
+// backends don't manage loading models
+func (r *Router) ListRunning(ctx context.Context) (*ollamaApi.ProcessResponse, error) {
+	return r.fallback.ListRunning(ctx)
+}
+
+// List merges the models of the default backend with the routed models
+func (r *Router) List(ctx context.Context) (*ollamaApi.ListResponse, error) {
+	resp, err := r.fallback.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[Backend]*ollamaApi.ListResponse)
+	for model, backend := range r.models {
+		if _, ok := listed[backend]; !ok {
+			other, err := backend.List(ctx)
+			if err != nil {
+				return nil, err
+			}
+			listed[backend] = other
+		}
+		for _, m := range listed[backend].Models {
+			if m.Model == model || m.Name == model {
+				resp.Models = append(resp.Models, m)
+			}
+		}
+	}
+	return resp, nil
+}
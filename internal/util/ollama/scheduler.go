@@ -0,0 +1,200 @@
+package ollama
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/stollenaar/ollamabot/internal/util"
+)
+
+// Queue schedules the generations of all commands and listeners
+var Queue *Scheduler
+
+func init() {
+	concurrency, err := strconv.Atoi(util.ConfigFile.QUEUE_CONCURRENCY)
+	if err != nil || concurrency < 1 {
+		log.Fatal("Error parsing QUEUE_CONCURRENCY: ", util.ConfigFile.QUEUE_CONCURRENCY)
+	}
+
+	limits := make(map[string]int)
+	for _, pair := range util.DeleteEmpty(strings.Split(util.ConfigFile.QUEUE_MODEL_CONCURRENCY, ",")) {
+		model, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			log.Fatal("Error parsing QUEUE_MODEL_CONCURRENCY: ", pair)
+		}
+		limits[model] = limit
+	}
+
+	Queue = NewScheduler(concurrency, limits)
+}
+
+// Scheduler bounds how many generations run at once per model. Waiting
+// requests are served first in first out, taking turns between users so one
+// user queueing many requests doesn't hold up everyone else.
+type Scheduler struct {
+	concurrency int
+	limits      map[string]int
+
+	mu     sync.Mutex
+	seq    int
+	queues map[string]*queue
+}
+
+// queue is the state of a single model. Every request gets a turn, one past
+// the last turn of its user but not before the turn being served, and the
+// waiting requests are served by turn and then by arrival.
+type queue struct {
+	running int
+	round   int
+	last    map[string]int
+	waiting []*waiter
+}
+
+type waiter struct {
+	seq      int
+	turn     int
+	ready    chan struct{}
+	position chan int
+	granted  bool
+	last     int
+}
+
+// NewScheduler creates a scheduler running concurrency generations per model
+// and host at once, limits overrides the concurrency of single models
+func NewScheduler(concurrency int, limits map[string]int) *Scheduler {
+	return &Scheduler{
+		concurrency: concurrency,
+		limits:      limits,
+		queues:      make(map[string]*queue),
+	}
+}
+
+// limit is the number of generations of the model that can run at once,
+// every healthy Ollama host with the model can run its own share
+func (s *Scheduler) limit(model string) int {
+	limit, ok := s.limits[model]
+	if !ok {
+		limit = s.concurrency
+	}
+	return limit * max(servingHosts(model), 1)
+}
+
+// servingHosts counts the healthy Ollama hosts that have the model
+func servingHosts(model string) (hosts int) {
+	model = normalizeModel(model)
+	for _, host := range Hosts() {
+		if host.Healthy && slices.Contains(host.Models, model) {
+			hosts++
+		}
+	}
+	return
+}
+
+// Acquire waits for a turn to generate with the model. While waiting, position
+// is called with the place in the queue whenever it changes, position may be
+// nil. The returned release function must be called when the generation ends.
+func (s *Scheduler) Acquire(ctx context.Context, model, user string, position func(int)) (release func(), err error) {
+	s.mu.Lock()
+	q, ok := s.queues[model]
+	if !ok {
+		q = &queue{last: make(map[string]int)}
+		s.queues[model] = q
+	}
+
+	turn := q.round
+	if last, ok := q.last[user]; ok {
+		turn = max(turn, last+1)
+	}
+	q.last[user] = turn
+
+	release = func() { s.release(model) }
+	if q.running < s.limit(model) && len(q.waiting) == 0 {
+		q.running++
+		s.mu.Unlock()
+		return release, nil
+	}
+
+	s.seq++
+	w := &waiter{
+		seq:      s.seq,
+		turn:     turn,
+		ready:    make(chan struct{}),
+		position: make(chan int, 1),
+	}
+	q.waiting = append(q.waiting, w)
+	q.reorder()
+	s.mu.Unlock()
+
+	for {
+		select {
+		case <-w.ready:
+			return release, nil
+		case n := <-w.position:
+			if position != nil {
+				position(n)
+			}
+		case <-ctx.Done():
+			s.mu.Lock()
+			if w.granted {
+				s.mu.Unlock()
+				release()
+				return nil, ctx.Err()
+			}
+			q.waiting = slices.DeleteFunc(q.waiting, func(other *waiter) bool { return other == w })
+			q.reorder()
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (s *Scheduler) release(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q := s.queues[model]
+	q.running--
+	for q.running < s.limit(model) && len(q.waiting) > 0 {
+		next := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		next.granted = true
+		close(next.ready)
+		q.running++
+		q.round = next.turn
+	}
+	maps.DeleteFunc(q.last, func(_ string, last int) bool { return last < q.round })
+	q.reorder()
+}
+
+// reorder sorts the waiting requests by turn and arrival and tells every
+// request whose place changed its new position
+func (q *queue) reorder() {
+	slices.SortFunc(q.waiting, func(a, b *waiter) int {
+		return cmp.Or(cmp.Compare(a.turn, b.turn), cmp.Compare(a.seq, b.seq))
+	})
+
+	for i, w := range q.waiting {
+		if w.last == i+1 {
+			continue
+		}
+		w.last = i + 1
+		select {
+		case <-w.position:
+		default:
+		}
+		w.position <- w.last
+	}
+}
+
+// QueueMessage is the message shown to a user waiting in the queue
+func QueueMessage(model string, position int) string {
+	return fmt.Sprintf("You are #%d in queue for %s, your answer starts once it's your turn", position, model)
+}
@@ -0,0 +1,70 @@
+package ollama
+
+import (
+	"log/slog"
+	"unicode/utf8"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+)
+
+// charsPerToken estimates the tokens of a prompt from its length
+const charsPerToken = 4
+
+// Usage counts the tokens of a chat to charge them, also when it is stopped
+// or times out before the done response reports the counts. The prompt of
+// such a chat is estimated from its length and every streamed chunk counts
+// as a token.
+type Usage struct {
+	model    string
+	prompt   int
+	chunks   int
+	reported int
+	done     bool
+}
+
+// NewUsage counts the tokens of the chat request
+func NewUsage(req *ollamaApi.ChatRequest) *Usage {
+	chars := 0
+	for _, message := range req.Messages {
+		chars += utf8.RuneCountInString(message.Content)
+	}
+	return &Usage{model: req.Model, prompt: (chars + charsPerToken - 1) / charsPerToken}
+}
+
+// Count wraps the response function of the chat to count its responses
+func (u *Usage) Count(fn ollamaApi.ChatResponseFunc) ollamaApi.ChatResponseFunc {
+	return func(cr ollamaApi.ChatResponse) error {
+		if cr.Done {
+			u.reported, u.done = cr.PromptEvalCount+cr.EvalCount, true
+		} else {
+			u.chunks++
+		}
+		return fn(cr)
+	}
+}
+
+// Tokens returns the tokens of the chat that ended with err, the reported
+// counts when it finished and an estimate when it was stopped or streamed
+// part of an answer. A chat failing before it started costs nothing.
+func (u *Usage) Tokens(err error) int {
+	switch {
+	case u.done:
+		return u.reported
+	case u.chunks > 0 || StopReason(err) != "":
+		return u.prompt + u.chunks
+	}
+	return 0
+}
+
+// Charge debits the tokens of the chat that ended with err from the user,
+// failures are logged
+func (u *Usage) Charge(userID string, err error) {
+	tokens := u.Tokens(err)
+	if tokens == 0 {
+		return
+	}
+	if _, err := database.ChargeUsage(userID, u.model, tokens); err != nil {
+		slog.Error("Error charging usage:", slog.Any("err", err))
+	}
+}
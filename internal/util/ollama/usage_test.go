@@ -0,0 +1,46 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+func TestUsageTokens(t *testing.T) {
+	// 16 characters estimate a prompt of 4 tokens
+	req := &ollamaApi.ChatRequest{
+		Model:    "model",
+		Messages: []ollamaApi.Message{{Role: "system", Content: "be nice"}, {Role: "user", Content: "hi there!"}},
+	}
+	chunk := ollamaApi.ChatResponse{Message: ollamaApi.Message{Content: "word"}}
+	done := ollamaApi.ChatResponse{Done: true, Metrics: ollamaApi.Metrics{PromptEvalCount: 10, EvalCount: 20}}
+
+	tests := []struct {
+		name      string
+		responses []ollamaApi.ChatResponse
+		err       error
+		want      int
+	}{
+		{name: "reported", responses: []ollamaApi.ChatResponse{chunk, chunk, done}, want: 30},
+		{name: "reported with a failed write", responses: []ollamaApi.ChatResponse{chunk, done}, err: errors.New("write failed"), want: 30},
+		{name: "stopped while streaming", responses: []ollamaApi.ChatResponse{chunk, chunk, chunk}, err: context.Canceled, want: 7},
+		{name: "timed out before streaming", err: context.DeadlineExceeded, want: 4},
+		{name: "failed while streaming", responses: []ollamaApi.ChatResponse{chunk}, err: errors.New("connection reset"), want: 5},
+		{name: "failed before streaming", err: errors.New("connection refused"), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := NewUsage(req)
+			fn := usage.Count(func(ollamaApi.ChatResponse) error { return nil })
+			for _, cr := range tt.responses {
+				fn(cr)
+			}
+			if got := usage.Tokens(tt.err); got != tt.want {
+				t.Errorf("got %d tokens, want %d", got, tt.want)
+			}
+		})
+	}
+}
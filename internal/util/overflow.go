@@ -0,0 +1,180 @@
+package util
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/disgoorg/disgo/discord"
+)
+
+// maxCodeFiles keeps the attachments within the 10 files Discord allows on a message
+const maxCodeFiles = 9
+
+var (
+	overflowMaxLength int
+	overflowCodeLines int
+
+	// codeExtensions maps the language tag of a code block to a file extension
+	codeExtensions = map[string]string{
+		"bash":       "sh",
+		"c":          "c",
+		"c++":        "cpp",
+		"cpp":        "cpp",
+		"cs":         "cs",
+		"csharp":     "cs",
+		"css":        "css",
+		"diff":       "diff",
+		"dockerfile": "dockerfile",
+		"go":         "go",
+		"golang":     "go",
+		"hcl":        "tf",
+		"html":       "html",
+		"java":       "java",
+		"javascript": "js",
+		"js":         "js",
+		"json":       "json",
+		"jsx":        "jsx",
+		"kotlin":     "kt",
+		"lua":        "lua",
+		"markdown":   "md",
+		"md":         "md",
+		"php":        "php",
+		"powershell": "ps1",
+		"py":         "py",
+		"python":     "py",
+		"rb":         "rb",
+		"ruby":       "rb",
+		"rs":         "rs",
+		"rust":       "rs",
+		"sh":         "sh",
+		"shell":      "sh",
+		"sql":        "sql",
+		"swift":      "swift",
+		"terraform":  "tf",
+		"toml":       "toml",
+		"ts":         "ts",
+		"tsx":        "tsx",
+		"typescript": "ts",
+		"xml":        "xml",
+		"yaml":       "yaml",
+		"yml":        "yaml",
+		"zsh":        "sh",
+	}
+)
+
+func init() {
+	var err error
+	overflowMaxLength, err = strconv.Atoi(ConfigFile.OVERFLOW_MAX_LENGTH)
+	if err != nil {
+		log.Fatal("Error parsing OVERFLOW_MAX_LENGTH: ", err)
+	}
+
+	overflowCodeLines, err = strconv.Atoi(ConfigFile.OVERFLOW_CODE_LINES)
+	if err != nil {
+		log.Fatal("Error parsing OVERFLOW_CODE_LINES: ", err)
+	}
+}
+
+// Overflow is a response that is posted as files with a short summary
+type Overflow struct {
+	Summary string
+	Files   []*discord.File
+}
+
+// codeBlock is a fenced code block taken out of a response
+type codeBlock struct {
+	language string
+	code     string
+}
+
+// CheckOverflow returns the overflow of content longer than
+// OVERFLOW_MAX_LENGTH or holding a code block of more than
+// OVERFLOW_CODE_LINES lines, and nil when it can be posted inline.
+// Setting either to 0 disables that check.
+func CheckOverflow(content string) *Overflow {
+	large := false
+	if overflowCodeLines > 0 {
+		_, blocks := splitCode(content)
+		for _, block := range blocks {
+			if strings.Count(block.code, "\n")+1 > overflowCodeLines {
+				large = true
+			}
+		}
+	}
+
+	if !large && (overflowMaxLength <= 0 || utf8.RuneCountInString(content) <= overflowMaxLength) {
+		return nil
+	}
+	return newOverflow(content)
+}
+
+// newOverflow attaches content as answer.md, with its code blocks as
+// separate files, behind a summary of the prose
+func newOverflow(content string) *Overflow {
+	prose, blocks := splitCode(content)
+	overflow := &Overflow{
+		Files: []*discord.File{
+			discord.NewFile("answer.md", "The full answer", strings.NewReader(content)),
+		},
+	}
+	for i, block := range blocks {
+		if i == maxCodeFiles {
+			break
+		}
+		overflow.Files = append(overflow.Files, discord.NewFile(
+			fmt.Sprintf("code-%d.%s", i+1, codeExtension(block.language)),
+			fmt.Sprintf("Code block %d of the answer", i+1),
+			strings.NewReader(block.code),
+		))
+	}
+
+	// The inline summary is the start of the prose around the code blocks
+	summary := BreakContent(prose, 500)
+	if len(summary) > 1 {
+		summary[0] += " …"
+	}
+	note := fmt.Sprintf("-# The full answer of %d characters is attached as answer.md", utf8.RuneCountInString(content))
+	if len(blocks) > 0 {
+		note += ", with the code blocks as separate files"
+	}
+	overflow.Summary = strings.TrimSpace(summary[0] + "\n" + note)
+	return overflow
+}
+
+// splitCode takes the fenced code blocks out of content, returning the
+// prose around them and the blocks without their fences
+func splitCode(content string) (string, []codeBlock) {
+	var prose strings.Builder
+	var blocks []codeBlock
+
+	for _, block := range splitBlocks(content) {
+		firstLine, rest, _ := strings.Cut(block.text, "\n")
+		fence := nextFence("", firstLine)
+		if fence == "" {
+			if prose.Len() > 0 {
+				prose.WriteString(block.sep)
+			}
+			prose.WriteString(block.text)
+			continue
+		}
+
+		if nextFence(fence, rest) == "" {
+			// Drop the closing fence
+			rest = rest[:max(strings.LastIndex(rest, "\n"), 0)]
+		}
+		language, _, _ := strings.Cut(strings.TrimSpace(strings.TrimLeft(fence, "`")), " ")
+		blocks = append(blocks, codeBlock{language: strings.ToLower(language), code: rest})
+	}
+	return prose.String(), blocks
+}
+
+// codeExtension returns the file extension for a language tag, defaulting to txt
+func codeExtension(language string) string {
+	if extension, ok := codeExtensions[language]; ok {
+		return extension
+	}
+	return "txt"
+}
@@ -0,0 +1,40 @@
+package util
+
+import (
+	"log/slog"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+)
+
+// NewQueueReply returns an update function that replies to the referenced
+// message with the given content on the first call and edits that reply
+// afterwards, done deletes the reply once the answer starts
+func NewQueueReply(client rest.Rest, reference discord.MessageReference) (update func(content string), done func()) {
+	var reply *discord.Message
+	update = func(content string) {
+		var err error
+		if reply == nil {
+			reply, err = client.CreateMessage(*reference.ChannelID, discord.MessageCreate{
+				Content:          content,
+				MessageReference: &reference,
+			})
+		} else {
+			_, err = client.UpdateMessage(*reference.ChannelID, reply.ID, discord.MessageUpdate{
+				Content: &content,
+			})
+		}
+		if err != nil {
+			slog.Error("Error updating the queue position:", slog.Any("err", err))
+		}
+	}
+	done = func() {
+		if reply == nil {
+			return
+		}
+		if err := client.DeleteMessage(*reference.ChannelID, reply.ID); err != nil {
+			slog.Error("Error deleting the queue position:", slog.Any("err", err))
+		}
+	}
+	return
+}
@@ -0,0 +1,71 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stollenaar/ollamabot/internal/database"
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+// documentChunkLength is how many characters of a document are embedded together
+const documentChunkLength = 1500
+
+// EmbedDocuments chunks the documents at markdown boundaries and embeds the
+// chunks for their knowledge base
+func EmbedDocuments(ctx context.Context, user string, documents ...database.KnowledgeDocument) (chunks []database.KnowledgeChunk, err error) {
+	var inputs []string
+	for _, document := range documents {
+		for _, content := range util.BreakContent(document.Content, documentChunkLength) {
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			chunks = append(chunks, database.KnowledgeChunk{
+				KnowledgeBase: document.KnowledgeBase,
+				FileName:      document.FileName,
+				Content:       content,
+				ModelName:     ollama.EmbeddingModel(),
+			})
+			// the file name often tells what the chunk is about
+			inputs = append(inputs, fmt.Sprintf("%s\n\n%s", document.FileName, content))
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	embeddings, err := Embed(ctx, user, inputs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chunks {
+		chunks[i].Embedding = embeddings[i]
+	}
+	return chunks, nil
+}
+
+// SearchKnowledgeBase returns the chunks of the knowledge base most similar
+// to the question as sources referring to their files
+func SearchKnowledgeBase(ctx context.Context, user, name, question string) (sources []Source, err error) {
+	embeddings, err := Embed(ctx, user, []string{question})
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := database.SearchKnowledgeChunks(name, ollama.EmbeddingModel(), embeddings[0], TopK)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, ErrOtherModel
+	}
+	for _, chunk := range chunks {
+		sources = append(sources, Source{
+			Content:   fmt.Sprintf("From %s:\n%s", chunk.FileName, chunk.Content),
+			Reference: fmt.Sprintf("`%s`", chunk.FileName),
+		})
+	}
+	return
+}
@@ -0,0 +1,69 @@
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/stollenaar/ollamabot/internal/util"
+	"github.com/stollenaar/ollamabot/internal/util/ollama"
+)
+
+var (
+	// TopK is how many chunks are retrieved to answer a question
+	TopK int
+
+	// ErrOtherModel is returned when nothing searched was embedded by the
+	// current embedding model
+	ErrOtherModel = errors.New("the chunks were embedded by another embedding model")
+)
+
+func init() {
+	var err error
+	TopK, err = strconv.Atoi(util.ConfigFile.RETRIEVAL_TOP_K)
+	if err != nil || TopK < 1 {
+		log.Fatal("Error parsing RETRIEVAL_TOP_K: must be a positive number")
+	}
+}
+
+// Embed embeds the inputs with the embedding model, waiting for its turn in
+// the queue of the model like the chats do
+func Embed(ctx context.Context, user string, inputs []string) ([][]float32, error) {
+	release, err := ollama.Queue.Acquire(ctx, ollama.EmbeddingModel(), user, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ollama.Embed(ctx, inputs)
+}
+
+// Source is a retrieved chunk and where it came from
+type Source struct {
+	Content string
+	// Reference is a message link or name of the source, shown in the citations
+	Reference string
+}
+
+// SystemPrompt instructs the model to answer from the sources, numbered
+// from 1 so the answer can cite them like [1]
+func SystemPrompt(sources []Source) string {
+	var prompt strings.Builder
+	prompt.WriteString("Answer the question using only the sources below. Cite the sources you use by their number, like [1]. " +
+		"If the sources don't contain the answer, say so instead of guessing.\n")
+	for i, source := range sources {
+		fmt.Fprintf(&prompt, "\n[%d]\n%s\n", i+1, source.Content)
+	}
+	return prompt.String()
+}
+
+// Citations lists the references of the sources by their number
+func Citations(sources []Source) string {
+	var citations []string
+	for i, source := range sources {
+		citations = append(citations, fmt.Sprintf("[%d] %s", i+1, source.Reference))
+	}
+	return "\n\n-# Sources\n" + strings.Join(citations, "\n")
+}
@@ -0,0 +1,273 @@
+package util
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+const (
+	// maxEmbedLength is the description limit of an embed
+	maxEmbedLength = 4096
+	// maxEmbedsLength is the embed text Discord allows on a message in total
+	maxEmbedsLength = 6000
+	// noAnswer is shown when a stream closes without content
+	noAnswer = "-# No answer"
+)
+
+var (
+	terminalRegex      *regexp.Regexp
+	streamEditInterval time.Duration
+)
+
+func init() {
+	var err error
+	terminalRegex, err = regexp.Compile(ConfigFile.TERMINAL_REGEX)
+	if err != nil {
+		log.Fatal("Error compiling TERMINAL_REGEX: ", err)
+	}
+
+	streamEditInterval, err = time.ParseDuration(ConfigFile.STREAM_EDIT_INTERVAL)
+	if err != nil {
+		log.Fatal("Error parsing STREAM_EDIT_INTERVAL: ", err)
+	}
+}
+
+// StreamBuffer collects streamed tokens and flushes the full content at a
+// cadence that stays within the Discord rate limits. A flush waits for the
+// edit interval and a sentence boundary matching TERMINAL_REGEX, unless no
+// boundary showed up for three intervals.
+type StreamBuffer struct {
+	content   strings.Builder
+	flushed   int
+	lastFlush time.Time
+	flush     func(content string, final bool) error
+}
+
+// NewStreamBuffer creates a stream buffer calling flush with the content
+// received so far, final is set for the flush made by Close
+func NewStreamBuffer(flush func(content string, final bool) error) *StreamBuffer {
+	return &StreamBuffer{flush: flush}
+}
+
+// NewReplyStream creates a stream buffer that replies to the referenced
+// message on the first flush and edits that reply afterwards. Content above
+// the Discord message limit continues in follow-up messages, unless the
+// final content overflows, then the reply becomes a summary with the full
+// answer attached. While streaming the reply carries a Stop button for the
+// request with stopID, the final flush removes it.
+func NewReplyStream(client rest.Rest, reference discord.MessageReference, stopID string) *StreamBuffer {
+	var replies []*discord.Message
+	var sent []string
+	return NewStreamBuffer(func(content string, final bool) error {
+		components := StopComponents(stopID)
+		if final {
+			components = []discord.LayoutComponent{}
+		}
+
+		if overflow := CheckOverflow(content); final && overflow != nil {
+			var err error
+			if len(replies) == 0 {
+				_, err = client.CreateMessage(*reference.ChannelID, discord.MessageCreate{
+					Content:          overflow.Summary,
+					Files:            overflow.Files,
+					MessageReference: &reference,
+				})
+			} else {
+				_, err = client.UpdateMessage(*reference.ChannelID, replies[0].ID, discord.MessageUpdate{
+					Content:    &overflow.Summary,
+					Files:      overflow.Files,
+					Components: &components,
+				})
+			}
+			if err != nil {
+				slog.Error("Error attaching the response:", slog.Any("err", err))
+				return nil
+			}
+
+			for _, reply := range replies[min(len(replies), 1):] {
+				if err := client.DeleteMessage(*reference.ChannelID, reply.ID); err != nil {
+					slog.Error("Error deleting a follow-up message:", slog.Any("err", err))
+				}
+			}
+			return nil
+		}
+
+		for i, chunk := range BreakContent(content, 2000) {
+			var err error
+			switch {
+			case i < len(sent) && sent[i] == chunk && (i > 0 || !final):
+				continue
+			case i < len(replies):
+				update := discord.MessageUpdate{Content: &chunk}
+				if i == 0 {
+					update.Components = &components
+				}
+				_, err = client.UpdateMessage(*reference.ChannelID, replies[i].ID, update)
+			default:
+				create := discord.MessageCreate{Content: chunk}
+				if i == 0 {
+					create.MessageReference = &reference
+					create.Components = components
+				}
+				var reply *discord.Message
+				reply, err = client.CreateMessage(*reference.ChannelID, create)
+				if err == nil {
+					replies = append(replies, reply)
+					sent = append(sent, "")
+				}
+			}
+
+			if err != nil {
+				slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", chunk))
+				return nil
+			}
+			sent[i] = chunk
+		}
+		return nil
+	})
+}
+
+// NewInteractionStream creates a stream buffer that edits the deferred
+// response of an interaction, the content is split over embeds to get around
+// the message limit. While streaming only the end of the content that fits
+// in the embeds of a message is shown, the final content becomes a summary
+// with the full answer attached when it overflows or doesn't fit. While
+// streaming the response carries a Stop button for the request with stopID,
+// the final flush removes it.
+func NewInteractionStream(client rest.Rest, applicationID snowflake.ID, token, stopID string) *StreamBuffer {
+	return NewStreamBuffer(func(content string, final bool) error {
+		contents := streamTail(content)
+
+		var embeds []discord.Embed
+		for _, content := range contents {
+			embeds = append(embeds, discord.Embed{
+				Description: content,
+			})
+		}
+		components := StopComponents(stopID)
+		if final {
+			components = []discord.LayoutComponent{}
+		}
+		update := discord.MessageUpdate{
+			Content:    new(string),
+			Embeds:     &embeds,
+			Components: &components,
+		}
+
+		if final {
+			overflow := CheckOverflow(content)
+			if overflow == nil && embedsLength(BreakContent(content, maxEmbedLength)) > maxEmbedsLength {
+				overflow = newOverflow(content)
+			}
+			if overflow != nil {
+				embeds = []discord.Embed{}
+				update.Content = &overflow.Summary
+				update.Files = overflow.Files
+			}
+		}
+
+		_, err := client.UpdateInteractionResponse(applicationID, token, update)
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", content))
+		}
+		return nil
+	})
+}
+
+// streamTail splits the end of content over embeds that stay within the
+// embed text Discord allows on a message. The shown part starts at a line,
+// after an ellipsis or the opening of the code block it starts in.
+func streamTail(content string) []string {
+	contents := BreakContent(content, maxEmbedLength)
+	runes := []rune(content)
+	for keep := min(len(runes), maxEmbedsLength) - 100; embedsLength(contents) > maxEmbedsLength && keep > 0; keep -= 100 {
+		cut := len(string(runes[:len(runes)-keep]))
+		if i := strings.IndexByte(content[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+
+		tail := "…\n"
+		if fence := nextFence("", content[:cut]); fence != "" {
+			tail = fence + "\n"
+		}
+		contents = BreakContent(tail+content[cut:], maxEmbedLength)
+	}
+	return contents
+}
+
+// embedsLength is the text of the embeds holding contents
+func embedsLength(contents []string) (length int) {
+	for _, content := range contents {
+		length += utf8.RuneCountInString(content)
+	}
+	return
+}
+
+// StopComponents returns the Stop button of the request with the id, nothing
+// when id is empty
+func StopComponents(id string) []discord.LayoutComponent {
+	if id == "" {
+		return []discord.LayoutComponent{}
+	}
+	return []discord.LayoutComponent{
+		discord.ActionRowComponent{
+			Components: []discord.InteractiveComponent{
+				discord.ButtonComponent{
+					Style:    discord.ButtonStyleDanger,
+					Label:    "Stop",
+					CustomID: fmt.Sprintf("stop_%s", id),
+				},
+			},
+		},
+	}
+}
+
+// Write appends a chunk and flushes when an edit is due
+func (s *StreamBuffer) Write(chunk string) error {
+	s.content.WriteString(chunk)
+
+	since := time.Since(s.lastFlush)
+	if since < streamEditInterval {
+		return nil
+	}
+	if !terminalRegex.MatchString(strings.TrimSpace(s.content.String())) && since < 3*streamEditInterval {
+		return nil
+	}
+	return s.Flush()
+}
+
+// Flush sends the content if anything changed since the last flush
+func (s *StreamBuffer) Flush() error {
+	if s.content.Len() == s.flushed || strings.TrimSpace(s.content.String()) == "" {
+		return nil
+	}
+	s.flushed = s.content.Len()
+	s.lastFlush = time.Now()
+	return s.flush(s.content.String(), false)
+}
+
+// Close sends the final content, which may be posted differently when it
+// overflows. Without content a note says there is no answer, so the message
+// doesn't keep waiting with its Stop button.
+func (s *StreamBuffer) Close() error {
+	s.flushed = s.content.Len()
+	s.lastFlush = time.Now()
+	if strings.TrimSpace(s.content.String()) == "" {
+		return s.flush(noAnswer, true)
+	}
+	return s.flush(s.content.String(), true)
+}
+
+// String returns the content received so far
+func (s *StreamBuffer) String() string {
+	return s.content.String()
+}
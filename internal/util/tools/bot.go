@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	ollamaApi "github.com/ollama/ollama/api"
+	"github.com/stollenaar/ollamabot/internal/database"
+)
+
+// The bot lookups answer about the bot itself, balances only of the person asking
+type (
+	botModels  struct{}
+	botBalance struct{}
+)
+
+func init() {
+	Register(botModels{})
+	Register(botBalance{})
+}
+
+func (botModels) Name() string {
+	return "bot_models"
+}
+
+func (botModels) Description() string {
+	return "Lists the models this bot offers with their details and how many tokens each platform gives per coin."
+}
+
+func (botModels) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{})
+}
+
+func (botModels) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	models, err := database.ListModelDetails()
+	if err != nil {
+		return "", err
+	}
+	prices, err := database.ListPlatformModels()
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	for _, model := range models {
+		line := model.Name
+		if details := model.Details(); details != "" {
+			line += " (" + details + ")"
+		}
+
+		var costs []string
+		for _, price := range prices[model.Name] {
+			costs = append(costs, fmt.Sprintf("%d tokens per coin on %s", price.Tokens, price.PlatformName))
+		}
+		if len(costs) == 0 {
+			costs = append(costs, "free")
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", line, strings.Join(costs, ", ")))
+	}
+	if len(lines) == 0 {
+		return "no models are added to the bot", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (botBalance) Name() string {
+	return "bot_balance"
+}
+
+func (botBalance) Description() string {
+	return "Returns how many coins the person asking has on each platform, coins pay for the priced models."
+}
+
+func (botBalance) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{})
+}
+
+func (botBalance) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	balances, err := database.ListBalances(env.UserID.String())
+	if err != nil {
+		return "", err
+	}
+	platforms, err := database.ListPlatforms()
+	if err != nil {
+		return "", err
+	}
+	names := make(map[string]string)
+	for _, platform := range platforms {
+		names[platform.ID] = platform.Name
+	}
+
+	var lines []string
+	for _, balance := range balances {
+		name, ok := names[balance.PlatformID]
+		if !ok {
+			name = balance.PlatformID
+		}
+		lines = append(lines, fmt.Sprintf("%s: %d coins", name, balance.Balance))
+	}
+	if len(lines) == 0 {
+		return "no coins yet, they can be bought with /topup", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
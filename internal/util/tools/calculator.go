@@ -0,0 +1,305 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"math"
+	"slices"
+	"strconv"
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// calculator evaluates arithmetic, the expression is parsed as a Go
+// expression and only numbers, operators and math functions are evaluated
+type calculator struct{}
+
+var (
+	mathFunctions = map[string]func(args ...float64) (float64, error){
+		"sqrt":  unary(math.Sqrt),
+		"abs":   unary(math.Abs),
+		"floor": unary(math.Floor),
+		"ceil":  unary(math.Ceil),
+		"round": unary(math.Round),
+		"sin":   unary(math.Sin),
+		"cos":   unary(math.Cos),
+		"tan":   unary(math.Tan),
+		"asin":  unary(math.Asin),
+		"acos":  unary(math.Acos),
+		"atan":  unary(math.Atan),
+		"ln":    unary(math.Log),
+		"log":   unary(math.Log10),
+		"log2":  unary(math.Log2),
+		"exp":   unary(math.Exp),
+		"pow": func(args ...float64) (float64, error) {
+			if len(args) != 2 {
+				return 0, errors.New("pow takes 2 arguments")
+			}
+			return math.Pow(args[0], args[1]), nil
+		},
+		"min": func(args ...float64) (float64, error) {
+			if len(args) == 0 {
+				return 0, errors.New("min takes at least 1 argument")
+			}
+			return slices.Min(args), nil
+		},
+		"max": func(args ...float64) (float64, error) {
+			if len(args) == 0 {
+				return 0, errors.New("max takes at least 1 argument")
+			}
+			return slices.Max(args), nil
+		},
+	}
+	mathConstants = map[string]float64{
+		"pi":  math.Pi,
+		"e":   math.E,
+		"phi": math.Phi,
+	}
+)
+
+func init() {
+	Register(calculator{})
+}
+
+func (calculator) Name() string {
+	return "calculator"
+}
+
+func (calculator) Description() string {
+	return "Evaluates an arithmetic expression exactly, use it instead of calculating in your head. " +
+		"Supports + - * / %, ** for powers, parentheses, the constants pi, e and phi and the functions " +
+		"sqrt, abs, floor, ceil, round, sin, cos, tan, asin, acos, atan, ln, log, log2, exp, pow, min and max."
+}
+
+func (calculator) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{
+		"expression": property("string", "The expression to evaluate, like (2 + 3) * sqrt(16)"),
+	}, "expression")
+}
+
+func (calculator) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	expression := stringArg(args, "expression")
+	if expression == "" {
+		return "", errors.New("expression is required")
+	}
+
+	result, err := Evaluate(expression)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(result, 'g', -1, 64), nil
+}
+
+// Evaluate calculates an arithmetic expression, ** is read as a power
+func Evaluate(expression string) (float64, error) {
+	// Go has no power operator and reads ^ as xor, both become pow calls
+	node, err := parser.ParseExpr(powerToCall(expression))
+	if err != nil {
+		return 0, fmt.Errorf("invalid expression: %w", err)
+	}
+	return evaluate(node)
+}
+
+func evaluate(node ast.Expr) (float64, error) {
+	switch node := node.(type) {
+	case *ast.BasicLit:
+		if node.Kind != token.INT && node.Kind != token.FLOAT {
+			return 0, fmt.Errorf("unsupported value %s", node.Value)
+		}
+		return strconv.ParseFloat(node.Value, 64)
+	case *ast.Ident:
+		if value, ok := mathConstants[node.Name]; ok {
+			return value, nil
+		}
+		return 0, fmt.Errorf("unknown constant %s", node.Name)
+	case *ast.ParenExpr:
+		return evaluate(node.X)
+	case *ast.UnaryExpr:
+		x, err := evaluate(node.X)
+		if err != nil {
+			return 0, err
+		}
+		switch node.Op {
+		case token.SUB:
+			return -x, nil
+		case token.ADD:
+			return x, nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", node.Op)
+	case *ast.BinaryExpr:
+		x, err := evaluate(node.X)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evaluate(node.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch node.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			if y == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return x / y, nil
+		case token.REM:
+			if y == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return math.Mod(x, y), nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", node.Op)
+	case *ast.CallExpr:
+		name, ok := node.Fun.(*ast.Ident)
+		if !ok {
+			return 0, errors.New("unsupported function call")
+		}
+		function, ok := mathFunctions[name.Name]
+		if !ok {
+			return 0, fmt.Errorf("unknown function %s", name.Name)
+		}
+		var args []float64
+		for _, arg := range node.Args {
+			value, err := evaluate(arg)
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, value)
+		}
+		return function(args...)
+	}
+	return 0, errors.New("unsupported expression")
+}
+
+// powerToCall rewrites a ** b and a ^ b to pow(a, b), binding tighter than
+// the other operators and to the right like in maths
+func powerToCall(expression string) string {
+	tokens := scanTokens(expression)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i] != "**" && tokens[i] != "^" {
+			continue
+		}
+		left := operandStart(tokens, i-1)
+		right := operandEnd(tokens, i+1)
+		if left < 0 || right >= len(tokens) {
+			continue
+		}
+
+		call := "pow(" + join(tokens[left:i]) + "," + join(tokens[i+1:right+1]) + ")"
+		tokens = append(tokens[:left], append([]string{call}, tokens[right+1:]...)...)
+		i = left
+	}
+	return join(tokens)
+}
+
+// scanTokens splits the expression into numbers, names, operators and parentheses
+func scanTokens(expression string) (tokens []string) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(expression))
+
+	var s scanner.Scanner
+	s.Init(file, []byte(expression), nil, 0)
+	for {
+		_, tok, literal := s.Scan()
+		switch {
+		case tok == token.EOF:
+			return
+		case tok == token.SEMICOLON && literal == "\n":
+			// inserted by the scanner at the end of the line
+			continue
+		case tok == token.MUL && len(tokens) > 0 && tokens[len(tokens)-1] == "*":
+			tokens[len(tokens)-1] = "**"
+			continue
+		case literal == "":
+			literal = tok.String()
+		}
+		tokens = append(tokens, literal)
+	}
+}
+
+// operandStart finds the first token of the operand ending at end
+func operandStart(tokens []string, end int) int {
+	if end < 0 {
+		return -1
+	}
+	if tokens[end] != ")" {
+		return end
+	}
+	depth := 0
+	for i := end; i >= 0; i-- {
+		switch tokens[i] {
+		case ")":
+			depth++
+		case "(":
+			depth--
+			if depth == 0 {
+				// a function call keeps its name
+				if i > 0 && isName(tokens[i-1]) {
+					return i - 1
+				}
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// operandEnd finds the last token of the operand starting at start, a sign
+// and the operand after it belong together
+func operandEnd(tokens []string, start int) int {
+	if start >= len(tokens) {
+		return len(tokens)
+	}
+	if tokens[start] == "-" || tokens[start] == "+" {
+		return operandEnd(tokens, start+1)
+	}
+	if isName(tokens[start]) && start+1 < len(tokens) && tokens[start+1] == "(" {
+		start++
+	}
+	if tokens[start] != "(" {
+		return start
+	}
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+func isName(tok string) bool {
+	return tok != "" && (tok[0] == '_' || tok[0] >= 'a' && tok[0] <= 'z' || tok[0] >= 'A' && tok[0] <= 'Z')
+}
+
+func join(tokens []string) (joined string) {
+	for _, tok := range tokens {
+		joined += tok + " "
+	}
+	return
+}
+
+func unary(fn func(float64) float64) func(args ...float64) (float64, error) {
+	return func(args ...float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, errors.New("the function takes 1 argument")
+		}
+		return fn(args[0]), nil
+	}
+}
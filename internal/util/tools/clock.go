@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"time"
+	_ "time/tzdata" // the time zones don't depend on the host having them installed
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// clock tells the current time in a time zone
+type clock struct{}
+
+func init() {
+	Register(clock{})
+}
+
+func (clock) Name() string {
+	return "current_time"
+}
+
+func (clock) Description() string {
+	return "Returns the current date and time in a time zone, models don't know the current time on their own."
+}
+
+func (clock) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{
+		"timezone": property("string", "An IANA time zone like Europe/Amsterdam or America/New_York, defaults to UTC"),
+	})
+}
+
+func (clock) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	name := stringArg(args, "timezone")
+	if name == "" {
+		name = "UTC"
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return "", fmt.Errorf("unknown time zone %s, use an IANA name like Europe/Amsterdam", name)
+	}
+	now := time.Now().In(location)
+	return fmt.Sprintf("%s (%s, %s)", now.Format(time.RFC3339), now.Format("Monday 2 January 2006 15:04"), location), nil
+}
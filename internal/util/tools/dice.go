@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+const (
+	maxDice  = 100
+	maxSides = 1000
+)
+
+// diceRegex matches dice notation like 2d6+1
+var diceRegex = regexp.MustCompile(`^(\d*)d(\d+)\s*([+-]\s*\d+)?$`)
+
+// dice rolls dice or picks a random number
+type dice struct{}
+
+func init() {
+	Register(dice{})
+}
+
+func (dice) Name() string {
+	return "random"
+}
+
+func (dice) Description() string {
+	return "Rolls dice in dice notation like 2d6+1, or picks a random whole number between min and max. " +
+		"Use it whenever a random outcome is needed instead of making one up."
+}
+
+func (dice) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{
+		"dice": property("string", "Dice to roll like d20 or 3d6+2, leave empty to pick a number between min and max"),
+		"min":  property("integer", "The lowest number to pick, defaults to 1"),
+		"max":  property("integer", "The highest number to pick, defaults to 100"),
+	})
+}
+
+func (dice) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	if notation := stringArg(args, "dice"); notation != "" {
+		return rollDice(notation)
+	}
+
+	low, err := numberArg(args, "min", 1)
+	if err != nil {
+		return "", err
+	}
+	high, err := numberArg(args, "max", 100)
+	if err != nil {
+		return "", err
+	}
+	if low > high {
+		return "", errors.New("min must not be above max")
+	}
+	return strconv.FormatInt(int64(low)+rand.Int64N(int64(high)-int64(low)+1), 10), nil
+}
+
+// rollDice rolls the dice of the notation, listing every roll and the total
+func rollDice(notation string) (string, error) {
+	match := diceRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(notation)))
+	if match == nil {
+		return "", fmt.Errorf("invalid dice %s, write them like 2d6+1", notation)
+	}
+
+	count := 1
+	if match[1] != "" {
+		count, _ = strconv.Atoi(match[1])
+	}
+	sides, _ := strconv.Atoi(match[2])
+	modifier := 0
+	if match[3] != "" {
+		modifier, _ = strconv.Atoi(strings.ReplaceAll(match[3], " ", ""))
+	}
+	if count < 1 || count > maxDice || sides < 2 || sides > maxSides {
+		return "", fmt.Errorf("roll between 1 and %d dice with 2 to %d sides", maxDice, maxSides)
+	}
+
+	rolls := make([]string, count)
+	total := modifier
+	for i := range rolls {
+		roll := rand.IntN(sides) + 1
+		rolls[i] = strconv.Itoa(roll)
+		total += roll
+	}
+	return fmt.Sprintf("rolls: %s, modifier: %+d, total: %d", strings.Join(rolls, ", "), modifier, total), nil
+}
@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// errNoGuild is returned by the Discord lookups outside of a server
+var errNoGuild = errors.New("this conversation is not in a server")
+
+// idRegex finds the id in a raw id or a user, channel or role mention
+var idRegex = regexp.MustCompile(`\d{15,21}`)
+
+// channelTypes names the channel types the lookups can come across
+var channelTypes = map[discord.ChannelType]string{
+	discord.ChannelTypeGuildText:          "text channel",
+	discord.ChannelTypeGuildVoice:         "voice channel",
+	discord.ChannelTypeGuildCategory:      "category",
+	discord.ChannelTypeGuildNews:          "announcement channel",
+	discord.ChannelTypeGuildNewsThread:    "announcement thread",
+	discord.ChannelTypeGuildPublicThread:  "public thread",
+	discord.ChannelTypeGuildPrivateThread: "private thread",
+	discord.ChannelTypeGuildStageVoice:    "stage channel",
+	discord.ChannelTypeGuildForum:         "forum",
+	discord.ChannelTypeGuildMedia:         "media channel",
+}
+
+// The lookups only answer about the server of the conversation
+type (
+	discordUser    struct{}
+	discordChannel struct{}
+	discordRole    struct{}
+)
+
+func init() {
+	Register(discordUser{})
+	Register(discordChannel{})
+	Register(discordRole{})
+}
+
+func (discordUser) Name() string {
+	return "discord_user"
+}
+
+func (discordUser) Description() string {
+	return "Looks up a member of this Discord server: names, when they joined, their roles and if they are a bot. " +
+		"Leave user empty to look up the person asking."
+}
+
+func (discordUser) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{
+		"user": property("string", "The user id or mention like <@123>"),
+	})
+}
+
+func (discordUser) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	if env.GuildID == 0 {
+		return "", errNoGuild
+	}
+	userID := env.UserID
+	if user := stringArg(args, "user"); user != "" {
+		var err error
+		if userID, err = parseID(user); err != nil {
+			return "", err
+		}
+	}
+
+	member, err := env.Client.GetMember(env.GuildID, userID)
+	if err != nil {
+		return "", fmt.Errorf("no member %s in this server", userID)
+	}
+	roles, err := env.Client.GetRoles(env.GuildID)
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for _, role := range roles {
+		for _, id := range member.RoleIDs {
+			if role.ID == id {
+				names = append(names, role.Name)
+			}
+		}
+	}
+
+	info := []string{
+		fmt.Sprintf("username: %s", member.User.Username),
+		fmt.Sprintf("display name: %s", member.EffectiveName()),
+		fmt.Sprintf("mention: <@%s>", member.User.ID),
+		fmt.Sprintf("account created: %s", member.User.CreatedAt().Format(time.DateOnly)),
+		fmt.Sprintf("bot: %t", member.User.Bot),
+		fmt.Sprintf("roles: %s", strings.Join(names, ", ")),
+	}
+	if member.JoinedAt != nil {
+		info = append(info, fmt.Sprintf("joined: %s", member.JoinedAt.Format(time.DateOnly)))
+	}
+	return strings.Join(info, "\n"), nil
+}
+
+func (discordChannel) Name() string {
+	return "discord_channel"
+}
+
+func (discordChannel) Description() string {
+	return "Looks up a channel or thread of this Discord server: its name, type, topic and when it was created."
+}
+
+func (discordChannel) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{
+		"channel": property("string", "The channel id or mention like <#123>"),
+	}, "channel")
+}
+
+func (discordChannel) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	if env.GuildID == 0 {
+		return "", errNoGuild
+	}
+	channelID, err := parseID(stringArg(args, "channel"))
+	if err != nil {
+		return "", err
+	}
+
+	channel, err := env.Client.GetChannel(channelID)
+	if err != nil {
+		return "", fmt.Errorf("no channel %s in this server", channelID)
+	}
+	guildChannel, ok := channel.(discord.GuildChannel)
+	if !ok || guildChannel.GuildID() != env.GuildID {
+		return "", fmt.Errorf("no channel %s in this server", channelID)
+	}
+
+	kind, ok := channelTypes[channel.Type()]
+	if !ok {
+		kind = "channel"
+	}
+	info := []string{
+		fmt.Sprintf("name: %s", channel.Name()),
+		fmt.Sprintf("type: %s", kind),
+		fmt.Sprintf("mention: <#%s>", channel.ID()),
+		fmt.Sprintf("created: %s", channel.CreatedAt().Format(time.DateOnly)),
+	}
+	if messageChannel, ok := channel.(discord.GuildMessageChannel); ok && messageChannel.Topic() != nil {
+		info = append(info, fmt.Sprintf("topic: %s", *messageChannel.Topic()))
+	}
+	return strings.Join(info, "\n"), nil
+}
+
+func (discordRole) Name() string {
+	return "discord_role"
+}
+
+func (discordRole) Description() string {
+	return "Looks up a role of this Discord server by id, mention or name: its color, position and if it is mentionable."
+}
+
+func (discordRole) Parameters() ollamaApi.ToolFunctionParameters {
+	return object(map[string]ollamaApi.ToolProperty{
+		"role": property("string", "The role id, mention like <@&123> or name"),
+	}, "role")
+}
+
+func (discordRole) Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error) {
+	if env.GuildID == 0 {
+		return "", errNoGuild
+	}
+	query := stringArg(args, "role")
+	if query == "" {
+		return "", errors.New("role is required")
+	}
+
+	roles, err := env.Client.GetRoles(env.GuildID)
+	if err != nil {
+		return "", err
+	}
+	id, _ := parseID(query)
+	for _, role := range roles {
+		if role.ID != id && !strings.EqualFold(role.Name, strings.TrimPrefix(query, "@")) {
+			continue
+		}
+		return strings.Join([]string{
+			fmt.Sprintf("name: %s", role.Name),
+			fmt.Sprintf("id: %s", role.ID),
+			fmt.Sprintf("color: #%06x", role.Color),
+			fmt.Sprintf("position: %d", role.Position),
+			fmt.Sprintf("shown separately: %t", role.Hoist),
+			fmt.Sprintf("mentionable: %t", role.Mentionable),
+			fmt.Sprintf("managed by an integration: %t", role.Managed),
+		}, "\n"), nil
+	}
+	return "", fmt.Errorf("no role %s in this server", query)
+}
+
+func parseID(text string) (snowflake.ID, error) {
+	match := idRegex.FindString(text)
+	if match == "" {
+		return 0, fmt.Errorf("%s is not a Discord id or mention", text)
+	}
+	return snowflake.Parse(match)
+}
@@ -0,0 +1,132 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+	ollamaApi "github.com/ollama/ollama/api"
+)
+
+// Tool is a function a model can call while answering. The tools only read
+// local state or Discord, none of them reach out to other services.
+type Tool interface {
+	// Name is the name the model calls the tool by
+	Name() string
+	// Description tells the model what the tool does
+	Description() string
+	// Parameters is the JSON schema of the arguments
+	Parameters() ollamaApi.ToolFunctionParameters
+	// Execute runs the tool, the result is handed back to the model
+	Execute(ctx context.Context, env Env, args ollamaApi.ToolCallFunctionArguments) (string, error)
+}
+
+// Env is what the tools can see of the conversation they are called from
+type Env struct {
+	Client rest.Rest
+	// GuildID is the guild of the conversation, zero in direct messages
+	GuildID snowflake.ID
+	// UserID is the user who asked
+	UserID snowflake.ID
+}
+
+var registry = make(map[string]Tool)
+
+// Register adds a tool to the tools offered to the models
+func Register(tool Tool) {
+	registry[tool.Name()] = tool
+}
+
+// Definitions returns the definitions of the registered tools for a chat request
+func Definitions() (definitions ollamaApi.Tools) {
+	for _, name := range slices.Sorted(maps.Keys(registry)) {
+		tool := registry[name]
+		definitions = append(definitions, ollamaApi.Tool{
+			Type: "function",
+			Function: ollamaApi.ToolFunction{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  tool.Parameters(),
+			},
+		})
+	}
+	return
+}
+
+// Call runs a tool call of a model. Failures are returned as the result so
+// the model can tell the user or try again.
+func Call(ctx context.Context, env Env, call ollamaApi.ToolCall) string {
+	tool, ok := registry[call.Function.Name]
+	if !ok {
+		return fmt.Sprintf("error: there is no tool named %s", call.Function.Name)
+	}
+
+	result, err := tool.Execute(ctx, env, call.Function.Arguments)
+	if err != nil {
+		slog.Error("Error executing tool:", slog.String("tool", call.Function.Name), slog.Any("err", err))
+		return fmt.Sprintf("error: %s", err)
+	}
+	return result
+}
+
+// object builds the schema of an object with the properties, the required
+// properties are listed in required
+func object(properties map[string]ollamaApi.ToolProperty, required ...string) ollamaApi.ToolFunctionParameters {
+	if required == nil {
+		required = []string{}
+	}
+	return ollamaApi.ToolFunctionParameters{
+		Type:       "object",
+		Required:   required,
+		Properties: properties,
+	}
+}
+
+func property(kind, description string) ollamaApi.ToolProperty {
+	return ollamaApi.ToolProperty{
+		Type:        ollamaApi.PropertyType{kind},
+		Description: description,
+	}
+}
+
+// stringArg returns an argument as text, models don't always stick to the
+// types of the schema
+func stringArg(args ollamaApi.ToolCallFunctionArguments, name string) string {
+	switch value := args[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(value)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// numberArg returns a numeric argument, fallback when it is missing
+func numberArg(args ollamaApi.ToolCallFunctionArguments, name string, fallback float64) (float64, error) {
+	switch value := args[name].(type) {
+	case nil:
+		return fallback, nil
+	case float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	case string:
+		if strings.TrimSpace(value) == "" {
+			return fallback, nil
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number", name)
+		}
+		return number, nil
+	default:
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/disgoorg/disgo/discord"
@@ -104,23 +104,6 @@ func GetSeparator() discord.SeparatorComponent {
 	}
 }
 
-
-func IntToInt32Slice(input []int) []int32 {
-	output := make([]int32, len(input))
-	for i, v := range input {
-		output[i] = int32(v)
-	}
-	return output
-}
-
-func Int32ToIntSlice(input []int32) []int {
-	output := make([]int, len(input))
-	for i, v := range input {
-		output[i] = int(v)
-	}
-	return output
-}
-
 // UpdateInteractionResponse updates the interaction response for application command events.
 func UpdateInteractionResponse(event *events.ApplicationCommandInteractionCreate, components []discord.LayoutComponent) {
 	_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
@@ -159,20 +142,24 @@ func RespondWithErrorComponent(event *events.ComponentInteractionCreate, err err
 	UpdateComponentInteractionResponse(event, components)
 }
 
-func BreakContent(content string, maxLength int) (result []string) {
-	words := strings.Split(content, " ")
-
-	var tmp string
-	for i, word := range words {
-		if i == 0 {
-			tmp = word
-		} else if len(tmp)+len(word) < maxLength {
-			tmp += " " + word
-		} else {
-			result = append(result, tmp)
-			tmp = word
+// ExtractModalSubmitData maps the custom ids of the submitted text inputs and
+// select menus to their values, a select menu to its first value
+func ExtractModalSubmitData(components iter.Seq[discord.Component]) map[string]string {
+	formData := make(map[string]string)
+	for component := range components {
+		switch c := component.(type) {
+		case discord.TextInputComponent:
+			formData[c.CustomID] = c.Value
+		case discord.StringSelectMenuComponent:
+			if len(c.Values) > 0 {
+				formData[c.CustomID] = c.Values[0]
+			}
 		}
 	}
-	result = append(result, tmp)
-	return result
+	return formData
+}
+
+// MessageLink returns the jump link to a Discord message
+func MessageLink(guildID, channelID, messageID string) string {
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, channelID, messageID)
 }